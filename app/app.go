@@ -1,140 +1,239 @@
 package app
 
 import (
-	"fmt"
+	"errors"
+	"net/http"
+	"sort"
 
 	"github.com/inconshreveable/log15"
 
+	"github.com/TrevorEdris/youtube-dependency-graph/internal/repository"
 	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/resolve"
 	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
 )
 
 type App interface {
-	GraphFromURL(url string) error
-	GraphFromTitle(title string) error
-	GraphFromID(id string) error
+	GraphFromURL(url string) (graph.Graph, error)
+	GraphFromTitle(title string) (graph.Graph, error)
+	GraphFromID(id string) (graph.Graph, error)
+	GraphFromPlaylist(playlist string) (graph.Graph, error)
+	GraphFromChannel(channel string, filter youtube.UploadsFilter) (graph.Graph, error)
+	GraphOfReferrers(id string, depth int) (graph.Graph, error)
+	EstimateFromURL(url string) (QuotaEstimate, error)
+	EstimateFromTitle(title string) (QuotaEstimate, error)
+	EstimateFromID(id string) (QuotaEstimate, error)
 }
 
 type app struct {
 	cfg    Config
-	client youtube.Client
-	log    log15.Logger
+	source youtube.Source
+	repo   repository.VideoRepository
+	quota  *youtube.QuotaTracker
+	// resolver follows links that aren't to YouTube, and is nil unless RESOLVE_LINKS is set
+	resolver resolve.Resolver
+	log      log15.Logger
 }
 
 func New(cfg Config, log log15.Logger) (App, error) {
-	client, err := youtube.NewClient(cfg.Youtube.APIKey, log)
+	var err error
+	var repo repository.VideoRepository
+	if cfg.Cache.Enabled || cfg.Youtube.UsesSource(SourceCache) {
+		repo, err = NewRepository(cfg)
+		if err != nil {
+			return &app{}, err
+		}
+	}
+
+	quota := youtube.NewQuotaTracker(cfg.Youtube.MaxQuota)
+	source, err := newSource(cfg, repo, quota, log)
 	if err != nil {
 		return &app{}, err
 	}
 
 	return &app{
-		cfg:    cfg,
-		client: client,
-		log:    log,
+		cfg:      cfg,
+		source:   source,
+		repo:     repo,
+		quota:    quota,
+		resolver: newResolver(cfg.Graph.Resolve),
+		log:      log,
 	}, nil
 }
 
-func (a *app) GraphFromURL(url string) error {
-	video, err := a.client.GetVideoByURL(url)
-	if err != nil {
-		return err
+// newResolver creates the resolve.Resolver described by rCfg, or nil if links aren't resolved.
+func newResolver(rCfg ResolveConfig) resolve.Resolver {
+	if !rCfg.Enabled {
+		return nil
+	}
+	opts := []resolve.Option{
+		resolve.WithHTTPClient(&http.Client{Timeout: rCfg.Timeout}),
+		resolve.WithMaxHops(rCfg.MaxHops),
+	}
+	if len(rCfg.AllowedHosts) > 0 {
+		opts = append(opts, resolve.WithAllowedHosts(rCfg.AllowedHosts...))
 	}
+	return resolve.New(opts...)
+}
 
-	return a.graphFromVideo(video)
+// NewRepository creates the VideoRepository described by cfg.Cache, in which fetched videos are cached.
+func NewRepository(cfg Config) (repository.VideoRepository, error) {
+	return repository.NewDiskRepository(cfg.Cache.Dir, cfg.Cache.TTL)
 }
 
-func (a *app) GraphFromTitle(title string) error {
-	video, err := a.client.GetVideoByTitle(title)
+func (a *app) GraphFromURL(url string) (graph.Graph, error) {
+	defer a.logQuota()
+
+	video, err := a.source.GetVideoByURL(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return a.graphFromVideo(video)
+	return a.graphFromVideo(video), nil
 }
 
-func (a *app) GraphFromID(id string) error {
-	video, err := a.client.GetVideoByID(id)
+func (a *app) GraphFromTitle(title string) (graph.Graph, error) {
+	defer a.logQuota()
+
+	video, err := a.source.GetVideoByTitle(title)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return a.graphFromVideo(video)
+	return a.graphFromVideo(video), nil
 }
 
-func (a *app) graphFromVideo(video youtube.Video) error {
-	a.log.Info("Generating graph for Video", "title", video.GetTitle(), "channel", video.GetChannelTitle())
-
-	// Create a new graph, letting it create a unique ID for the graph
-	g := graph.NewGraph("", "Youtube Video Dependencies", "ydg")
+func (a *app) GraphFromID(id string) (graph.Graph, error) {
+	defer a.logQuota()
 
-	refs, err := a.getReferences(g, video, 0)
+	video, err := a.source.GetVideoByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.log.Debug("Recursion completed")
 
-	for _, vid := range refs {
-		if vid == nil {
-			continue
-		}
-		a.log.Debug("Video Reference", "title", vid.GetTitle(), "channel", vid.GetChannelTitle())
-	}
+	return a.graphFromVideo(video), nil
+}
 
-	fmt.Println(g.ToCustomJSON())
+// GraphFromPlaylist creates the graph of every video in the playlist, which may be a playlist
+// URL or ID, along with their references.
+func (a *app) GraphFromPlaylist(playlist string) (graph.Graph, error) {
+	defer a.logQuota()
 
-	return nil
+	url, err := youtube.NewPlaylistURL(playlist)
+	if err != nil {
+		return nil, err
+	}
+	pl, err := youtube.GetPlaylist(a.source, url.GetID(), a.cfg.Graph.MaxPlaylistItems)
+	if err != nil {
+		return nil, err
+	}
+
+	a.log.Info("Generating graph for Playlist", "title", pl.GetTitle(), "channel", pl.GetChannelTitle(), "videos", pl.GetItemCount())
+	g := graph.NewGraph("", "Youtube Video Dependencies", "ydg")
+	state := a.crawlPlaylist(g, pl)
+	a.logCrawl(state)
+	return g, nil
 }
 
-func (a *app) getReferences(g graph.Graph, video youtube.Video, currentDepth int) ([]youtube.Video, error) {
-	a.log.Debug(fmt.Sprintf("======================[ %s, %s, %d ]======================", video.GetID(), video.GetChannelID(), currentDepth))
-	a.log.Debug("Base video", "title", video.GetTitle(), "channel", video.GetChannelTitle())
+// GraphFromChannel creates a single graph of every video uploaded to the channel that passes
+// filter, along with their references. The channel may be a channel ID, an @handle or a link to
+// the channel. Once crawled, the references from the videos that were seen but never walked,
+// such as those at MAX_DEPTH, to the other videos seen are filled in too.
+func (a *app) GraphFromChannel(channel string, filter youtube.UploadsFilter) (graph.Graph, error) {
+	defer a.logQuota()
 
-	parentNode, err := graph.NewNode(video.GetID(), video.GetTitle())
+	ref, err := youtube.ParseChannelRef(channel)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := youtube.GetChannel(a.source, ref)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := youtube.GetUploads(a.source, ch, filter)
 	if err != nil {
-		return []youtube.Video{}, nil
+		return nil, err
 	}
-	g.AddNode(parentNode)
 
-	// TODO: Does this need to be the very top of the function?
-	// Stop recursion once we reach the maximum recursion depth
-	if currentDepth > a.cfg.Graph.MaxDepth {
-		return []youtube.Video{}, nil
+	a.log.Info("Generating graph for Channel", "title", ch.GetTitle(), "id", ch.GetID(), "uploads", len(ids))
+	videos, err := a.source.GetVideosByIDs(ids)
+	var missingErr *youtube.MissingVideosError
+	if errors.As(err, &missingErr) {
+		a.log.Warn("Unable to find uploads", "ids", missingErr.IDs)
+	} else if err != nil {
+		return nil, err
 	}
 
-	// Get all the URLs from the description of video
-	urls := video.GetUrlsFromDescription()
-	allRefs := make([]youtube.Video, len(urls)+1)
-	allRefs = append(allRefs, video)
+	// The uploads are the roots of the crawl, most recent first
+	uploads := []youtube.Video{}
+	for _, id := range ids {
+		if vid, ok := videos[id]; ok {
+			uploads = append(uploads, vid)
+		}
+	}
 
-	// For each of the URLs in the description
-	for _, url := range urls {
+	g := graph.NewGraph("", "Youtube Video Dependencies", "ydg")
+	state := a.crawl(g, uploads...)
+	a.addReverseEdges(g, state)
+	a.logCrawl(state)
+	return g, nil
+}
 
-		// Get the Video that the URL is referencing
-		referencedVideo, err := a.client.GetVideoByURL(url)
-		if err != nil {
-			a.log.Warn("Unable to get video", "input", url, "error", err)
-			continue
-		}
+func (a *app) graphFromVideo(video youtube.Video) graph.Graph {
+	a.log.Info("Generating graph for Video", "title", video.GetTitle(), "channel", video.GetChannelTitle())
 
-		childNode, err := graph.NewNode(referencedVideo.GetID(), referencedVideo.GetTitle())
-		if err != nil {
-			a.log.Warn("Unable to create new node from referenced video", "input", referencedVideo.GetChannelID(), "error", err)
-			continue
-		}
+	// Create a new graph, letting it create a unique ID for the graph
+	g := graph.NewGraph("", "Youtube Video Dependencies", "ydg")
+
+	state := a.crawl(g, video)
+	a.logCrawl(state)
+	return g
+}
 
-		g.AddEdge(parentNode, childNode, "references_via_description")
-		a.log.Debug("Video reference", "title", referencedVideo.GetTitle(), "url", url, "channel", video.GetChannelTitle())
+// logCrawl logs a summary of a completed crawl.
+func (a *app) logCrawl(state *crawlState) {
+	a.log.Info("Crawl completed", "videosFetched", len(state.videos), "playlistsFetched", len(state.playlists), "commentsSearched", state.comments, "linksResolved", state.resolved, "externalNodes", len(state.external), "reverseEdges", state.reverse, "videosExpanded", len(state.expanded), "duplicateFetchesAvoided", state.avoided)
+	if len(state.broken) > 0 {
+		a.log.Warn("Some references could not be resolved to videos", brokenSummary(state)...)
+	}
+	if state.stopped {
+		a.log.Warn("Quota spent before the crawl completed, the graph is partial", "maxQuota", a.cfg.Youtube.MaxQuota)
+	}
 
-		// Get all the URLs in the referenced video's description
-		childVids, err := a.getReferences(g, referencedVideo, currentDepth+1)
-		if err != nil {
-			a.log.Error("Unable to getReferences", "referencedVideoID", referencedVideo.GetID(), "currentDepth", currentDepth, "error", err)
-			continue
-		}
+	for _, id := range state.order {
+		vid := state.videos[id]
+		a.log.Debug("Video Reference", "title", vid.GetTitle(), "channel", vid.GetChannelTitle(), "depth", state.depth[id])
+	}
+}
 
-		// Append all the referenced video's URLs to the overall list of URLs
-		allRefs = append(allRefs, childVids...)
+// logQuota logs a summary of the quota units spent during the run.
+func (a *app) logQuota() {
+	summary := a.quota.Summary()
+	ctx := []interface{}{"units", summary.Units}
+	if summary.Budget > 0 {
+		ctx = append(ctx, "budget", summary.Budget)
 	}
+	for _, usage := range summary.Calls {
+		ctx = append(ctx, usage.Call+".calls", usage.Calls, usage.Call+".units", usage.Units)
+	}
+	a.log.Info("Quota usage", ctx...)
+}
+
+// brokenSummary returns log context counting the broken references of state by status.
+func brokenSummary(state *crawlState) []interface{} {
+	counts := map[string]int{}
+	statuses := []string{}
+	for _, broken := range state.broken {
+		if counts[broken.status] == 0 {
+			statuses = append(statuses, broken.status)
+		}
+		counts[broken.status]++
+	}
+	sort.Strings(statuses)
 
-	return allRefs, nil
+	ctx := []interface{}{}
+	for _, status := range statuses {
+		ctx = append(ctx, status, counts[status])
+	}
+	return ctx
 }
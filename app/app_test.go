@@ -0,0 +1,471 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/recorder"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/resolve"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+var uuidRegex = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// newFixtureApp creates an app crawling the fixtures in testdata/fixtures, without a cache,
+// applying each of opts to its config.
+func newFixtureApp(t *testing.T, maxDepth int, opts ...func(cfg *Config)) App {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	cfg := Config{
+		Youtube: YoutubeClientConfig{
+			Source:      []string{SourceFixture},
+			FixturesDir: filepath.Join("testdata", "fixtures"),
+			Retry:       RetryConfig{MaxAttempts: 1},
+			Record:      RecordConfig{Mode: recorder.ModeOff},
+		},
+		Log:    LogConfig{LogLevel: "info", LogFmt: "logfmt"},
+		Graph:  GraphConfig{MaxDepth: maxDepth, CrawlConcurrency: 4, MaxPlaylistItems: 3, Comments: CommentsOff, MaxCommentPages: 1},
+		Output: OutputConfig{Format: graph.FormatCustomJSON},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	require.NoError(t, cfg.Validate(), "expected the fixture config to be valid")
+
+	a, err := New(cfg, log)
+	require.NoError(t, err, "New produced an unexpected error")
+	return a
+}
+
+// requireGolden compares g, encoded in each format, against the golden files named after name.
+// Random IDs are normalized first, so that the output of a crawl is reproducible.
+func requireGolden(t *testing.T, name string, g graph.Graph) {
+	for _, format := range []string{graph.FormatJGF, graph.FormatDOT} {
+		out, err := graph.Encode(g, format)
+		require.NoError(t, err, "Encode produced an unexpected error")
+		out = uuidRegex.ReplaceAllString(out, "<uuid>")
+
+		path := filepath.Join("testdata", "golden", name+"."+format)
+		if *update {
+			require.NoError(t, ioutil.WriteFile(path, []byte(out), 0644), "unable to update golden file")
+		}
+		expected, err := ioutil.ReadFile(path)
+		require.NoError(t, err, "unable to read golden file, run the tests with -update to create it")
+		require.Equal(t, string(expected), out, "output differs from %s", path)
+	}
+}
+
+func TestGraphFromID_Golden(t *testing.T) {
+	tests := []struct {
+		name     string
+		root     string
+		maxDepth int
+	}{
+		{name: "series", root: "series00003", maxDepth: 3},
+		{name: "series_depth0", root: "series00003", maxDepth: 0},
+		{name: "quota_exceeded", root: "budget00001", maxDepth: 3},
+		{name: "playlist_reference", root: "season00001", maxDepth: 1},
+		{name: "chapters", root: "chapter0001", maxDepth: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newFixtureApp(t, tt.maxDepth).GraphFromID(tt.root)
+			require.NoError(t, err, "GraphFromID produced an unexpected error")
+			requireGolden(t, tt.name, g)
+		})
+	}
+}
+
+// countingSource counts the lookups of each video ID made through it.
+type countingSource struct {
+	youtube.Source
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (s *countingSource) count(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.lookups[id]++
+	}
+}
+
+func (s *countingSource) GetVideoByID(id string) (youtube.Video, error) {
+	s.count(id)
+	return s.Source.GetVideoByID(id)
+}
+
+func (s *countingSource) GetVideosByIDs(ids []string) (map[string]youtube.Video, error) {
+	s.count(ids...)
+	return s.Source.GetVideosByIDs(ids)
+}
+
+func TestGraphFromID_Cycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		root     string
+		maxDepth int
+		// backEdge is an edge closing a cycle, which must be recorded even though its target is not walked again
+		backEdge [2]string
+	}{
+		{name: "cycle through the root", root: "series00002", maxDepth: 3, backEdge: [2]string{"series00003", "series00002"}},
+		{name: "cycle below the root", root: "series00003", maxDepth: 3, backEdge: [2]string{"series00002", "series00003"}},
+		{name: "cycle at max depth", root: "series00002", maxDepth: 1, backEdge: [2]string{"series00003", "series00002"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newFixtureApp(t, tt.maxDepth).(*app)
+			source := &countingSource{Source: a.source, lookups: map[string]int{}}
+			a.source = source
+
+			g, err := a.GraphFromID(tt.root)
+			require.NoError(t, err, "GraphFromID produced an unexpected error")
+			require.NotEmpty(t, source.lookups, "expected the videos to be fetched through the counting source")
+			for id, n := range source.lookups {
+				require.Equal(t, 1, n, "expected %s to be fetched once", id)
+			}
+			require.Contains(t, edges(t, g), tt.backEdge, "expected the back-edge %s -> %s to be recorded", tt.backEdge[0], tt.backEdge[1])
+		})
+	}
+}
+
+// edges returns the source and target of every edge of g.
+func edges(t *testing.T, g graph.Graph) [][2]string {
+	var custom struct {
+		Edges []struct {
+			Source string `json:"source"`
+			Target string `json:"target"`
+		} `json:"edges"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(g.ToCustomJSON()), &custom))
+	res := [][2]string{}
+	for _, e := range custom.Edges {
+		res = append(res, [2]string{e.Source, e.Target})
+	}
+	return res
+}
+
+func TestGraphFromTitle_Fixture(t *testing.T) {
+	g, err := newFixtureApp(t, 3).GraphFromTitle("the finale")
+	require.NoError(t, err, "GraphFromTitle produced an unexpected error")
+	requireGolden(t, "series", g)
+
+	_, err = newFixtureApp(t, 3).GraphFromTitle("no such video")
+	require.ErrorIs(t, err, youtube.ErrVideoNotFound)
+}
+
+func TestGraphFromURL_Fixture(t *testing.T) {
+	_, err := newFixtureApp(t, 3).GraphFromURL("https://youtu.be/private0000")
+	require.ErrorIs(t, err, youtube.ErrPrivateVideo)
+
+	_, err = newFixtureApp(t, 3).GraphFromURL("https://example.com/not-a-video")
+	require.ErrorIs(t, err, youtube.ErrInvalidURL)
+}
+
+func TestGraphFromPlaylist_Golden(t *testing.T) {
+	g, err := newFixtureApp(t, 1).GraphFromPlaylist("https://www.youtube.com/playlist?list=PLseries000000000001")
+	require.NoError(t, err, "GraphFromPlaylist produced an unexpected error")
+	requireGolden(t, "playlist", g)
+
+	_, err = newFixtureApp(t, 1).GraphFromPlaylist("PLmissing0000000000")
+	require.ErrorIs(t, err, youtube.ErrPlaylistNotFound)
+}
+
+func TestGraphFromChannel_Golden(t *testing.T) {
+	filter := youtube.UploadsFilter{
+		PublishedAfter:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		PublishedBefore: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		MaxVideos:       2,
+	}
+	g, err := newFixtureApp(t, 1).GraphFromChannel("https://www.youtube.com/@SeriesChannel", filter)
+	require.NoError(t, err, "GraphFromChannel produced an unexpected error")
+	requireGolden(t, "channel", g)
+
+	_, err = newFixtureApp(t, 1).GraphFromChannel("@nobody", filter)
+	require.ErrorIs(t, err, youtube.ErrChannelNotFound)
+}
+
+func TestGraphFromID_Comments(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments string
+	}{
+		{name: "comments_off", comments: CommentsOff},
+		{name: "comments_channel", comments: CommentsChannel},
+		{name: "comments_all", comments: CommentsAll},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newFixtureApp(t, 0, func(cfg *Config) { cfg.Graph.Comments = tt.comments }).GraphFromID("bonus000001")
+			require.NoError(t, err, "GraphFromID produced an unexpected error")
+			requireGolden(t, tt.name, g)
+		})
+	}
+}
+
+// barrierSource holds the comment lookups on the videos in hold until all of them are in flight,
+// or a second has passed.
+type barrierSource struct {
+	youtube.Source
+	hold    map[string]bool
+	release chan struct{}
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (s *barrierSource) GetComments(vid youtube.Video, filter youtube.CommentFilter) ([]youtube.Comment, error) {
+	if !s.hold[vid.GetID()] {
+		return youtube.GetComments(s.Source, vid, filter)
+	}
+
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxSeen {
+		s.maxSeen = s.inFlight
+	}
+	if s.inFlight == len(s.hold) {
+		close(s.release)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.release:
+	case <-time.After(time.Second):
+	}
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return youtube.GetComments(s.Source, vid, filter)
+}
+
+func TestGraphFromID_CommentsConcurrent(t *testing.T) {
+	a := newFixtureApp(t, 1, func(cfg *Config) { cfg.Graph.Comments = CommentsAll }).(*app)
+	// series00001 and series00002 make up the frontier at depth 1
+	source := &barrierSource{Source: a.source, hold: map[string]bool{"series00001": true, "series00002": true}, release: make(chan struct{})}
+	a.source = source
+
+	_, err := a.GraphFromID("series00003")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+	require.Equal(t, 2, source.maxSeen, "expected the comments on the frontier to be fetched concurrently")
+}
+
+// redirectTransport sends every request to server, whatever its host, recording the hosts requested.
+type redirectTransport struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	hosts  []string
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.hosts = append(rt.hosts, req.URL.Host)
+	rt.mu.Unlock()
+
+	u, err := neturl.Parse(rt.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return rt.server.Client().Transport.RoundTrip(req)
+}
+
+func TestGraphFromID_MaxQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxQuota int64
+		nodes    int
+		units    int64
+	}{
+		// The root, then a single batch of the videos referenced at each level below it
+		{name: "unlimited", maxQuota: 0, nodes: 8, units: 5},
+		{name: "spent on the root", maxQuota: 1, nodes: 1, units: 1},
+		{name: "spent on the first level", maxQuota: 2, nodes: 5, units: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newFixtureApp(t, 3, func(cfg *Config) { cfg.Youtube.MaxQuota = tt.maxQuota })
+			g, err := a.GraphFromID("series00003")
+			require.NoError(t, err, "expected the crawl to stop with a partial graph once the budget is spent")
+			require.Len(t, g.GetNodes(), tt.nodes)
+
+			summary := a.(*app).quota.Summary()
+			require.Equal(t, tt.units, summary.Units)
+			require.Equal(t, []youtube.CallUsage{{Call: youtube.CallVideosList, Calls: tt.units, Units: tt.units * youtube.CostVideosList}}, summary.Calls)
+		})
+	}
+}
+
+func TestGraphFromID_Resolve(t *testing.T) {
+	redirects := map[string]string{
+		"/3series1":        "https://www.youtube.com/watch?v=series00001&t=42",
+		"/series-playlist": "https://www.youtube.com/playlist?list=PLseries000000000001",
+		"/3merch":          "https://shop.example.com/",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, ok := redirects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}))
+	t.Cleanup(server.Close)
+	transport := &redirectTransport{server: server}
+
+	a := newFixtureApp(t, 0, func(cfg *Config) {
+		cfg.Graph.Resolve = ResolveConfig{Enabled: true, MaxHops: 5, Timeout: time.Second}
+	})
+	// The default allowlist is kept, so that only the shortened links are followed
+	a.(*app).resolver = resolve.New(resolve.WithHTTPClient(&http.Client{Transport: transport}))
+
+	g, err := a.GraphFromID("short000001")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+	requireGolden(t, "resolve", g)
+	require.ElementsMatch(t, []string{"bit.ly", "tinyurl.com", "bit.ly", "bit.ly"}, transport.hosts, "expected each shortened link, and no other link, to be followed once")
+
+	g, err = newFixtureApp(t, 0).GraphFromID("short000001")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+	require.Len(t, g.GetEdges(), 1, "expected links to be left unresolved unless RESOLVE_LINKS is set")
+}
+
+func TestGraphFromID_External(t *testing.T) {
+	g, err := newFixtureApp(t, 1, func(cfg *Config) { cfg.Graph.ExternalLinks = true }).GraphFromID("paper000001")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+	requireGolden(t, "external", g)
+
+	g, err = newFixtureApp(t, 1).GraphFromID("paper000001")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+	require.Len(t, g.GetNodes(), 2, "expected external links to be left out unless EXTERNAL_LINKS is set")
+}
+
+func TestClassifyRelation(t *testing.T) {
+	tests := []struct {
+		context  string
+		relation string
+	}{
+		{context: "Previous episode", relation: relationReferencesPrevious},
+		{context: "Missed Part 1? Watch it here", relation: relationReferencesPrevious},
+		{context: "Watch our original Quantum Tunneling episode here", relation: relationReferencesOriginal},
+		{context: "The sequel to our original episode", relation: relationReferencesSequel},
+		{context: "Next episode", relation: relationReferencesSequel},
+		{context: "Part 12 of the series", relation: ""},
+		{context: "Based on my earlier work", relation: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			relation, ok := classifyRelation(tt.context)
+			require.Equal(t, tt.relation != "", ok)
+			require.Equal(t, tt.relation, relation)
+		})
+	}
+}
+
+func TestAddEdge_MergesRelations(t *testing.T) {
+	description := reference{context: "Based on my earlier work"}
+	comment := reference{comment: true, context: "The follow-up to this one"}
+	original := reference{context: "Watch our original episode"}
+
+	tests := []struct {
+		name     string
+		refs     []reference
+		relation string
+		label    string
+	}{
+		{name: "more specific second", refs: []reference{description, comment}, relation: relationReferencesSequel, label: "Based on my earlier work"},
+		{name: "more specific first", refs: []reference{comment, description}, relation: relationReferencesSequel, label: "The follow-up to this one"},
+		{name: "equally specific", refs: []reference{original, comment}, relation: relationReferencesOriginal, label: "Watch our original episode"},
+		{name: "unclassified", refs: []reference{description, {comment: true}}, relation: relationReferencesViaDescription, label: "Based on my earlier work"},
+		{name: "without context", refs: []reference{{}, comment}, relation: relationReferencesSequel, label: "The follow-up to this one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := graph.NewGraph("", "", "")
+			parent, err := graph.NewNode("parent", "Parent")
+			require.NoError(t, err, "NewNode produced an unexpected error")
+			child, err := graph.NewNode("child", "Child")
+			require.NoError(t, err, "NewNode produced an unexpected error")
+
+			for _, ref := range tt.refs {
+				addEdge(g, parent, child, ref)
+			}
+			edges := g.GetEdges()
+			require.Len(t, edges, 1, "expected the references to share an edge")
+			require.Equal(t, tt.relation, edges[0].GetRelation())
+			require.Contains(t, edges[0].ToJSON(), `"label":"`+tt.label+`"`, "expected the edge to keep the context it was labeled with")
+		})
+	}
+}
+
+func TestGraphFromChannel_ReverseEdges(t *testing.T) {
+	filter := youtube.UploadsFilter{
+		PublishedAfter:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		PublishedBefore: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	g, err := newFixtureApp(t, 0).GraphFromChannel("@serieschannel", filter)
+	require.NoError(t, err, "GraphFromChannel produced an unexpected error")
+	requireGolden(t, "channel_reverse", g)
+}
+
+func TestAddReverseEdges_Stopped(t *testing.T) {
+	a := newFixtureApp(t, 3).(*app)
+	sequel, err := a.source.GetVideoByID("series00002")
+	require.NoError(t, err, "GetVideoByID produced an unexpected error")
+	pilot, err := a.source.GetVideoByID("series00001")
+	require.NoError(t, err, "GetVideoByID produced an unexpected error")
+
+	// Both videos were expanded, but the quota was spent before the references of the level were fetched
+	state := newCrawlState()
+	state.add(pilot, 0)
+	state.add(sequel, 1)
+	state.expanded[pilot.GetID()] = true
+	state.expanded[sequel.GetID()] = true
+	state.stopped = true
+
+	g := graph.NewGraph("", "", "")
+	a.addReverseEdges(g, state)
+	require.Len(t, g.GetEdges(), 1, "expected the references of videos that were never fetched to be filled in")
+	require.Equal(t, 1, state.reverse)
+
+	state.walked[sequel.GetID()] = true
+	g = graph.NewGraph("", "", "")
+	a.addReverseEdges(g, state)
+	require.Empty(t, g.GetEdges(), "expected walked videos to be skipped")
+}
+
+func TestGraphOfReferrers_Golden(t *testing.T) {
+	a := newFixtureApp(t, 3, func(cfg *Config) { cfg.Cache = CacheConfig{Enabled: true, Dir: t.TempDir()} })
+	_, err := a.GraphFromID("series00003")
+	require.NoError(t, err, "GraphFromID produced an unexpected error")
+
+	g, err := a.GraphOfReferrers("https://youtu.be/series00001", 2)
+	require.NoError(t, err, "GraphOfReferrers produced an unexpected error")
+	requireGolden(t, "referrers", g)
+
+	_, err = a.GraphOfReferrers("series00001", enforcedMaximumDepth+1)
+	require.Error(t, err, "expected a depth beyond the maximum to be rejected")
+
+	_, err = newFixtureApp(t, 2).GraphOfReferrers("series00001", 2)
+	require.ErrorIs(t, err, errNoRepository)
+}
@@ -4,29 +4,57 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/recorder"
 )
 
 const (
-	enforcedMaximumDepth = 10
+	enforcedMaximumDepth       = 10
+	enforcedMaximumConcurrency = 32
 )
 
 var (
-	errEmptyAPIKey   = errors.New("missing required environment variable API_KEY")
-	errEmptyLogFmt   = errors.New("missing required environment variable LOG_FMT")
-	errEmptyLogLevel = errors.New("missing required environment variable LOG_LEVEL")
+	errEmptyAPIKey      = errors.New("missing required environment variable API_KEY")
+	errEmptyFixturesDir = errors.New("missing required environment variable FIXTURES_DIR")
+	errEmptyLogFmt      = errors.New("missing required environment variable LOG_FMT")
+	errEmptyLogLevel    = errors.New("missing required environment variable LOG_LEVEL")
 )
 
 type Config struct {
 	Youtube YoutubeClientConfig
 	Log     LogConfig
 	Graph   GraphConfig
+	Cache   CacheConfig
+	Output  OutputConfig
 }
 
 type YoutubeClientConfig struct {
-	APIKey string `envconfig:"API_KEY" required:"true"`
+	Source      []string `envconfig:"SOURCE" default:"api"`
+	APIKey      string   `envconfig:"API_KEY"`
+	FixturesDir string   `envconfig:"FIXTURES_DIR"`
+	MaxQuota    int64    `envconfig:"MAX_QUOTA" default:"0"`
+	Retry       RetryConfig
+	Record      RecordConfig
+}
+
+type RetryConfig struct {
+	MaxAttempts int           `envconfig:"RETRY_MAX_ATTEMPTS" default:"3"`
+	BaseDelay   time.Duration `envconfig:"RETRY_BASE_DELAY" default:"500ms"`
+	MaxDelay    time.Duration `envconfig:"RETRY_MAX_DELAY" default:"10s"`
+}
+
+// RecordConfig describes whether the HTTP responses of the api and scrape sources are recorded
+// to cassettes, or replayed from them.
+type RecordConfig struct {
+	Mode string `envconfig:"RECORD_MODE" default:"off"`
+	Dir  string `envconfig:"CASSETTE_DIR"`
 }
 
 type LogConfig struct {
@@ -35,7 +63,46 @@ type LogConfig struct {
 }
 
 type GraphConfig struct {
-	MaxDepth int `envconfig:"MAX_DEPTH" default:"3"`
+	MaxDepth         int `envconfig:"MAX_DEPTH" default:"3"`
+	CrawlConcurrency int `envconfig:"CRAWL_CONCURRENCY" default:"4"`
+	MaxPlaylistItems int `envconfig:"MAX_PLAYLIST_ITEMS" default:"200"`
+	// Comments is whose top-level comments are searched for references, in addition to the description
+	Comments        string `envconfig:"COMMENTS" default:"off"`
+	MaxCommentPages int    `envconfig:"MAX_COMMENT_PAGES" default:"1"`
+	// ExternalLinks keeps the links that aren't to YouTube, such as papers, as external nodes
+	ExternalLinks bool `envconfig:"EXTERNAL_LINKS" default:"false"`
+	Resolve       ResolveConfig
+}
+
+// ResolveConfig describes whether links that aren't to YouTube, such as shortened links, are
+// followed to find the videos and playlists they redirect to.
+type ResolveConfig struct {
+	Enabled bool `envconfig:"RESOLVE_LINKS" default:"false"`
+	MaxHops int  `envconfig:"RESOLVE_MAX_HOPS" default:"5"`
+	// AllowedHosts are the hosts whose links are followed, where empty is resolve.DefaultAllowedHosts
+	AllowedHosts []string      `envconfig:"RESOLVE_ALLOWED_HOSTS"`
+	Timeout      time.Duration `envconfig:"RESOLVE_TIMEOUT" default:"10s"`
+}
+
+// Authors of the comments searched for references, as selected by COMMENTS.
+const (
+	CommentsOff     = "off"
+	CommentsChannel = "channel"
+	CommentsAll     = "all"
+)
+
+var validComments = []string{CommentsOff, CommentsChannel, CommentsAll}
+
+type CacheConfig struct {
+	Enabled bool          `envconfig:"CACHE_ENABLED" default:"true"`
+	Dir     string        `envconfig:"CACHE_DIR"`
+	TTL     time.Duration `envconfig:"CACHE_TTL" default:"168h"`
+}
+
+type OutputConfig struct {
+	Format    string `envconfig:"OUTPUT_FORMAT" default:"custom-json"`
+	Path      string `envconfig:"OUTPUT_PATH"`
+	Aggregate string `envconfig:"OUTPUT_AGGREGATE"`
 }
 
 func ParseConfig() (Config, error) {
@@ -50,6 +117,19 @@ func ParseConfig() (Config, error) {
 		return Config{}, err
 	}
 
+	for i, src := range cfg.Youtube.Source {
+		cfg.Youtube.Source[i] = strings.ToLower(strings.TrimSpace(src))
+	}
+	cfg.Youtube.Record.Mode = strings.ToLower(strings.TrimSpace(cfg.Youtube.Record.Mode))
+	cfg.Graph.Comments = strings.ToLower(strings.TrimSpace(cfg.Graph.Comments))
+
+	if cfg.Cache.Dir == "" {
+		cfg.Cache.Dir, err = defaultCacheDir()
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	err = cfg.Validate()
 	if err != nil {
 		return Config{}, err
@@ -74,13 +154,80 @@ func (cfg Config) Validate() error {
 		return err
 	}
 
+	err = cfg.Cache.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.Output.Validate()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (yCfg YoutubeClientConfig) Validate() error {
-	if yCfg.APIKey == "" {
+	if len(yCfg.Source) == 0 {
+		return fmt.Errorf("missing required environment variable SOURCE")
+	}
+	sources := map[string]bool{}
+	for _, src := range validSources {
+		sources[src] = true
+	}
+	for _, src := range yCfg.Source {
+		if !sources[src] {
+			return fmt.Errorf("provided SOURCE (%s) invalid; Must be a comma-separated list of %s", src, strings.Join(validSources, ", "))
+		}
+	}
+	// The API key is only required if the API may be called
+	if yCfg.UsesSource(SourceAPI) && yCfg.Record.Mode != recorder.ModeReplay && yCfg.APIKey == "" {
 		return errEmptyAPIKey
 	}
+	if yCfg.UsesSource(SourceFixture) && yCfg.FixturesDir == "" {
+		return errEmptyFixturesDir
+	}
+	if yCfg.MaxQuota < 0 {
+		return fmt.Errorf("provided MAX_QUOTA (%d) invalid; Must not be negative", yCfg.MaxQuota)
+	}
+	err := yCfg.Retry.Validate()
+	if err != nil {
+		return err
+	}
+	return yCfg.Record.Validate()
+}
+
+// UsesSource returns true if name is one of the sources listed in SOURCE.
+func (yCfg YoutubeClientConfig) UsesSource(name string) bool {
+	for _, src := range yCfg.Source {
+		if src == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (rCfg RetryConfig) Validate() error {
+	if rCfg.MaxAttempts < 1 {
+		return fmt.Errorf("provided RETRY_MAX_ATTEMPTS (%d) invalid; Must be at least 1", rCfg.MaxAttempts)
+	}
+	if rCfg.BaseDelay < 0 || rCfg.MaxDelay < rCfg.BaseDelay {
+		return fmt.Errorf("provided RETRY_BASE_DELAY (%s) and RETRY_MAX_DELAY (%s) invalid; Must satisfy 0 <= RETRY_BASE_DELAY <= RETRY_MAX_DELAY", rCfg.BaseDelay, rCfg.MaxDelay)
+	}
+	return nil
+}
+
+func (rCfg RecordConfig) Validate() error {
+	modes := map[string]bool{}
+	for _, mode := range recorder.Modes {
+		modes[mode] = true
+	}
+	if !modes[rCfg.Mode] {
+		return fmt.Errorf("provided RECORD_MODE (%s) invalid; Must be one of %s", rCfg.Mode, strings.Join(recorder.Modes, ", "))
+	}
+	if rCfg.Mode != recorder.ModeOff && rCfg.Dir == "" {
+		return fmt.Errorf("missing required environment variable CASSETTE_DIR for RECORD_MODE %s", rCfg.Mode)
+	}
 	return nil
 }
 
@@ -98,5 +245,65 @@ func (gCfg GraphConfig) Validate() error {
 	if gCfg.MaxDepth > enforcedMaximumDepth {
 		return fmt.Errorf("provided MAX_DEPTH (%d) too high; Must be lower than %d", gCfg.MaxDepth, enforcedMaximumDepth)
 	}
+	if gCfg.CrawlConcurrency < 1 || gCfg.CrawlConcurrency > enforcedMaximumConcurrency {
+		return fmt.Errorf("provided CRAWL_CONCURRENCY (%d) invalid; Must be between 1 and %d", gCfg.CrawlConcurrency, enforcedMaximumConcurrency)
+	}
+	if gCfg.MaxPlaylistItems < 0 {
+		return fmt.Errorf("provided MAX_PLAYLIST_ITEMS (%d) invalid; Must not be negative", gCfg.MaxPlaylistItems)
+	}
+	switch gCfg.Comments {
+	case CommentsOff, CommentsChannel, CommentsAll:
+	default:
+		return fmt.Errorf("provided COMMENTS (%s) invalid; Must be one of %s", gCfg.Comments, strings.Join(validComments, ", "))
+	}
+	if gCfg.MaxCommentPages < 1 {
+		return fmt.Errorf("provided MAX_COMMENT_PAGES (%d) invalid; Must be at least 1", gCfg.MaxCommentPages)
+	}
+	return gCfg.Resolve.Validate()
+}
+
+func (rCfg ResolveConfig) Validate() error {
+	if !rCfg.Enabled {
+		return nil
+	}
+	if rCfg.MaxHops < 1 {
+		return fmt.Errorf("provided RESOLVE_MAX_HOPS (%d) invalid; Must be at least 1", rCfg.MaxHops)
+	}
+	if rCfg.Timeout <= 0 {
+		return fmt.Errorf("provided RESOLVE_TIMEOUT (%s) invalid; Must be positive", rCfg.Timeout)
+	}
 	return nil
 }
+
+func (cCfg CacheConfig) Validate() error {
+	if cCfg.TTL < 0 {
+		return fmt.Errorf("provided CACHE_TTL (%s) invalid; Must not be negative", cCfg.TTL)
+	}
+	return nil
+}
+
+// defaultCacheDir returns the ydg directory beneath the user's cache directory.
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine default CACHE_DIR: %s", err)
+	}
+	return filepath.Join(dir, "ydg"), nil
+}
+
+func (oCfg OutputConfig) Validate() error {
+	aggregations := map[string]bool{graph.AggregateNone: true}
+	for _, aggregate := range graph.Aggregations {
+		aggregations[aggregate] = true
+	}
+	if !aggregations[strings.ToLower(strings.TrimSpace(oCfg.Aggregate))] {
+		return fmt.Errorf("provided OUTPUT_AGGREGATE (%s) invalid; Must be empty or one of %s", oCfg.Aggregate, strings.Join(graph.Aggregations, ", "))
+	}
+
+	for _, format := range graph.Formats {
+		if strings.EqualFold(strings.TrimSpace(oCfg.Format), format) {
+			return nil
+		}
+	}
+	return fmt.Errorf("provided OUTPUT_FORMAT (%s) invalid; Must be one of %s", oCfg.Format, strings.Join(graph.Formats, ", "))
+}
@@ -0,0 +1,813 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/internal/repository"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/external"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/resolve"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// crawlState tracks which videos have been seen during a single run, allowing
+// the crawler to record cycles without fetching or expanding a video twice.
+type crawlState struct {
+	// videos contains every video fetched so far, keyed by video ID
+	videos map[string]youtube.Video
+	// playlists contains every playlist fetched so far, keyed by playlist ID
+	playlists map[string]youtube.Playlist
+	// depth contains the shortest distance from the root, keyed by video or playlist ID
+	depth map[string]int
+	// order contains the IDs of every fetched video, in the order they were discovered
+	order []string
+	// expanded contains the IDs of videos whose descriptions have already been walked
+	expanded map[string]bool
+	// walked contains the IDs of expanded videos whose references were all fetched
+	walked map[string]bool
+	// comments counts the comments searched for references
+	comments int
+	// resolved counts the links that redirected to a video or playlist
+	resolved int
+	// external contains the IDs of every external node added so far
+	external map[string]bool
+	// reverse counts the edges added from videos that were seen but never walked
+	reverse int
+	// avoided counts the references that were resolved from videos instead of the client
+	avoided int
+	// broken contains the references that could not be resolved to a video or playlist, keyed by ID
+	broken map[string]brokenReference
+	// stopped is set once the quota budget or the API quota is spent, ending the crawl after the current level
+	stopped bool
+}
+
+func newCrawlState() *crawlState {
+	return &crawlState{
+		videos:    map[string]youtube.Video{},
+		playlists: map[string]youtube.Playlist{},
+		depth:     map[string]int{},
+		order:     []string{},
+		expanded:  map[string]bool{},
+		walked:    map[string]bool{},
+		broken:    map[string]brokenReference{},
+		external:  map[string]bool{},
+	}
+}
+
+// add records vid as discovered at the given depth.
+func (s *crawlState) add(vid youtube.Video, depth int) {
+	s.videos[vid.GetID()] = vid
+	s.depth[vid.GetID()] = depth
+	s.order = append(s.order, vid.GetID())
+}
+
+// Relations of the edges added by the crawler.
+const (
+	relationReferencesViaDescription = "references_via_description"
+	relationReferencesViaComment     = "references_via_comment"
+	relationReferencesPrevious       = "references_previous"
+	relationReferencesSequel         = "references_sequel"
+	relationReferencesOriginal       = "references_original"
+	relationContains                 = "contains"
+	relationCitesExternal            = "cites_external"
+)
+
+// relationKeywords classify a reference by the text describing it. The rules are checked in
+// order, so that "the sequel to our original episode" references a sequel.
+var relationKeywords = []struct {
+	relation string
+	keywords *regexp.Regexp
+}{
+	{relationReferencesSequel, regexp.MustCompile(`(?i)\b(?:sequel|follow[- ]?up|next (?:episode|part|video))\b`)},
+	{relationReferencesPrevious, regexp.MustCompile(`(?i)\b(?:previous|part (?:1|one)|last (?:episode|video|time))\b`)},
+	{relationReferencesOriginal, regexp.MustCompile(`(?i)\boriginal\b`)},
+}
+
+// classifyRelation returns the relation matching the keywords of context, and false if none match.
+func classifyRelation(context string) (string, bool) {
+	for _, rule := range relationKeywords {
+		if rule.keywords.MatchString(context) {
+			return rule.relation, true
+		}
+	}
+	return "", false
+}
+
+// moreSpecific reports whether relation says more about a reference than other, which is the
+// case for a relation classified by the keywords of its context over any other.
+func moreSpecific(relation, other string) bool {
+	return isClassified(relation) && !isClassified(other)
+}
+
+// isClassified reports whether relation is one of relationKeywords.
+func isClassified(relation string) bool {
+	for _, rule := range relationKeywords {
+		if rule.relation == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// reference is a single link found in the description of a video or one of its comments, or a
+// single item of a playlist. Links that aren't to YouTube are references to an external object.
+type reference struct {
+	// parent is the video whose description or comment contains the link, unless playlist is set
+	parent youtube.Video
+	// comment is set if the link was found in a comment on parent rather than its description
+	comment bool
+	// playlist is the playlist containing the referenced video, if the reference is a playlist item
+	playlist youtube.Playlist
+	url      string
+	// id is the ID of the referenced video or playlist, or url if no ID could be parsed out of it
+	id string
+	// parseErr is set if no video ID could be parsed out of url
+	parseErr error
+	// timestamp is the time into the referenced video that url links to
+	timestamp time.Duration
+	// chapter is the chapter of the parent's description or comment that the link appears under
+	chapter *youtube.Chapter
+	// context is the text describing the link, such as the line it appears on
+	context string
+	// originalURL is the link as written, if it redirected to url, such as a shortened link
+	originalURL string
+	// external is the object outside YouTube that the link refers to, if any
+	external *external.Reference
+}
+
+// relation returns the relation of the edge from the parent of ref to the referenced video or
+// playlist, classified by the context of the link when its keywords say what was referenced.
+func (ref reference) relation() string {
+	if ref.playlist != nil {
+		return relationContains
+	}
+	if ref.external != nil {
+		return relationCitesExternal
+	}
+	if relation, ok := classifyRelation(ref.context); ok {
+		return relation
+	}
+	if ref.comment {
+		return relationReferencesViaComment
+	}
+	return relationReferencesViaDescription
+}
+
+// edgeMetadata returns the metadata of the edge from the parent of ref to the referenced video
+// or playlist, and false if there is nothing to describe beyond its relation.
+func (ref reference) edgeMetadata() (graph.EdgeMetadata, bool) {
+	md := graph.EdgeMetadata{Context: ref.context, OriginalURL: ref.originalURL}
+	if ref.timestamp > 0 {
+		md.Timestamps = []int{int(ref.timestamp / time.Second)}
+	}
+	if ref.chapter != nil {
+		md.Chapters = []graph.Chapter{{Title: ref.chapter.Title, Start: int(ref.chapter.Start / time.Second)}}
+	}
+	return md, md.Context != "" || len(md.Timestamps) > 0 || len(md.Chapters) > 0 || md.OriginalURL != ""
+}
+
+// addEdge adds the edge for ref from parentNode to childNode, described by the metadata of ref.
+// If parentNode already references childNode, such as from both its description and a comment,
+// the references share the edge, which keeps the more specific of their relations.
+func addEdge(g graph.Graph, parentNode, childNode graph.Node, ref reference) {
+	relation := ref.relation()
+	if e, ok := g.GetEdge(parentNode, childNode); ok && !moreSpecific(relation, e.GetRelation()) {
+		relation = e.GetRelation()
+	}
+	if md, ok := ref.edgeMetadata(); ok {
+		g.AddEdgeWithMetadata(parentNode, childNode, relation, md)
+		return
+	}
+	g.AddEdge(parentNode, childNode, relation)
+}
+
+// parentTitle returns the title of the video or playlist containing ref.
+func (ref reference) parentTitle() string {
+	if ref.playlist != nil {
+		return ref.playlist.GetTitle()
+	}
+	return ref.parent.GetTitle()
+}
+
+// brokenReference describes why a referenced video could not be fetched.
+type brokenReference struct {
+	status string
+	// label is the last known title of the video, if any
+	label string
+	err   error
+}
+
+// fetchResult is the outcome of a single batched client call made by a crawl worker.
+type fetchResult struct {
+	ids    []string
+	videos map[string]youtube.Video
+	err    error
+}
+
+// lookupResult is the outcome of the calls made by a crawl worker for a single video of the
+// frontier, before its references are collected.
+type lookupResult struct {
+	// comments are the comments on the video selected by COMMENTS
+	comments    []youtube.Comment
+	commentsErr error
+	// resolved contains where each link outside YouTube in the description and comments
+	// redirects, keyed by link, and is nil unless RESOLVE_LINKS is set
+	resolved map[string]resolution
+}
+
+// resolution is the outcome of following a single link.
+type resolution struct {
+	target string
+	err    error
+}
+
+// crawl walks the references of roots breadth-first, adding every video and reference
+// to g. Because each level of the frontier is fully resolved before the next one is
+// started, the depth of each video is the shortest distance from any of the roots.
+func (a *app) crawl(g graph.Graph, roots ...youtube.Video) *crawlState {
+	state := newCrawlState()
+	frontier := []youtube.Video{}
+	for _, root := range roots {
+		if _, ok := state.videos[root.GetID()]; ok {
+			continue
+		}
+		state.add(root, 0)
+		frontier = append(frontier, root)
+
+		rootNode, err := newVideoNode(state, root)
+		if err == nil {
+			g.AddNode(rootNode)
+		}
+	}
+
+	a.walk(g, state, frontier, []reference{})
+	return state
+}
+
+// crawlPlaylist walks the videos of root, and their references, breadth-first the same
+// way crawl does, with the videos of root at depth 1.
+func (a *app) crawlPlaylist(g graph.Graph, root youtube.Playlist) *crawlState {
+	state := newCrawlState()
+	state.playlists[root.GetID()] = root
+	state.depth[root.GetID()] = 0
+
+	rootNode, err := newPlaylistNode(state, root)
+	if err == nil {
+		g.AddNode(rootNode)
+	}
+
+	a.walk(g, state, []youtube.Video{}, a.playlistItems(root))
+	return state
+}
+
+// walk resolves the references of the frontier, and the playlist items, one level at a time
+// until MAX_DEPTH is reached. Each level is resolved fully before the next one is started.
+func (a *app) walk(g graph.Graph, state *crawlState, frontier []youtube.Video, items []reference) {
+	for depth := 0; (len(frontier) > 0 || len(items) > 0) && depth <= a.cfg.Graph.MaxDepth && !state.stopped; depth++ {
+		a.log.Debug(fmt.Sprintf("======================[ depth %d, %d videos, %d playlist items ]======================", depth, len(frontier), len(items)))
+
+		refs, playlistRefs, externalRefs := a.collectReferences(state, frontier)
+		refs = append(items, refs...)
+		a.fetchReferences(state, refs, depth+1)
+		// If the crawl stopped during the level, some references of the frontier were never fetched
+		if !state.stopped {
+			for _, video := range frontier {
+				state.walked[video.GetID()] = true
+			}
+		}
+		items = a.addPlaylists(g, state, playlistRefs, depth+1)
+		a.addExternal(g, state, externalRefs)
+
+		frontier = []youtube.Video{}
+		for _, ref := range refs {
+			parentNode, err := parentNode(state, ref)
+			if err != nil {
+				a.log.Warn("Unable to create new node from parent", "input", ref.parentTitle(), "error", err)
+				continue
+			}
+
+			referencedVideo, ok := state.videos[ref.id]
+			if !ok {
+				a.addBrokenReference(g, state, parentNode, ref)
+				continue
+			}
+
+			childNode, err := newVideoNode(state, referencedVideo)
+			if err != nil {
+				a.log.Warn("Unable to create new node from referenced video", "input", referencedVideo.GetChannelID(), "error", err)
+				continue
+			}
+
+			addEdge(g, parentNode, childNode, ref)
+			a.log.Debug("Video reference", "title", referencedVideo.GetTitle(), "url", ref.url, "parent", ref.parentTitle())
+
+			// Back-edges and cycles are recorded above, but only videos discovered at
+			// this depth are walked in the next level
+			if !state.expanded[ref.id] && state.depth[ref.id] == depth+1 {
+				state.expanded[ref.id] = true
+				frontier = append(frontier, referencedVideo)
+			}
+		}
+	}
+}
+
+// addPlaylists fetches every playlist in playlistRefs that was not already fetched during
+// this run, recording them at the given depth, and adds an edge to each playlist from the
+// video referencing it. The items of the newly fetched playlists are returned, to be walked
+// in the next level.
+func (a *app) addPlaylists(g graph.Graph, state *crawlState, playlistRefs []reference, depth int) []reference {
+	items := []reference{}
+	for _, ref := range playlistRefs {
+		_, fetched := state.playlists[ref.id]
+		_, broken := state.broken[ref.id]
+		if !fetched && !broken && !state.stopped {
+			pl, err := youtube.GetPlaylist(a.source, ref.id, a.cfg.Graph.MaxPlaylistItems)
+			switch {
+			case err == nil:
+				state.playlists[ref.id] = pl
+				state.depth[ref.id] = depth
+				items = append(items, a.playlistItems(pl)...)
+			case youtube.IsQuotaError(err):
+				a.log.Warn("Quota spent, stopping crawl", "playlist", ref.id, "error", err)
+				state.stopped = true
+			default:
+				a.log.Warn("Unable to get playlist", "input", ref.url, "error", err)
+				state.broken[ref.id] = brokenReference{status: statusFromError(err), err: err}
+			}
+		} else if fetched || broken {
+			state.avoided++
+		}
+
+		parentNode, err := parentNode(state, ref)
+		if err != nil {
+			a.log.Warn("Unable to create new node from video", "input", ref.parent.GetID(), "error", err)
+			continue
+		}
+		pl, ok := state.playlists[ref.id]
+		if !ok {
+			a.addBrokenReference(g, state, parentNode, ref)
+			continue
+		}
+		childNode, err := newPlaylistNode(state, pl)
+		if err != nil {
+			a.log.Warn("Unable to create new node from referenced playlist", "input", pl.GetID(), "error", err)
+			continue
+		}
+		addEdge(g, parentNode, childNode, ref)
+		a.log.Debug("Playlist reference", "title", pl.GetTitle(), "url", ref.url, "parent", ref.parentTitle())
+	}
+	return items
+}
+
+// addExternal adds a node for every external object in externalRefs, shared by every reference to
+// the same object, and an edge to each of them from the video referencing it.
+func (a *app) addExternal(g graph.Graph, state *crawlState, externalRefs []reference) {
+	for _, ref := range externalRefs {
+		parentNode, err := parentNode(state, ref)
+		if err != nil {
+			a.log.Warn("Unable to create new node from video", "input", ref.parent.GetID(), "error", err)
+			continue
+		}
+		childNode, err := graph.NewExternalNode(ref.id, ref.external.ID, graph.ExternalMetadata{
+			Kind:   ref.external.Kind,
+			Domain: ref.external.Domain,
+			URL:    ref.external.URL,
+		})
+		if err != nil {
+			a.log.Warn("Unable to create new node from external link", "input", ref.url, "error", err)
+			continue
+		}
+
+		if !state.external[ref.id] {
+			state.external[ref.id] = true
+			g.AddNode(childNode)
+		}
+		addEdge(g, parentNode, childNode, ref)
+		a.log.Debug("External reference", "id", ref.id, "url", ref.url, "parent", ref.parentTitle())
+	}
+}
+
+// playlistItems returns a reference to each video in pl, which was looked up with at most
+// MAX_PLAYLIST_ITEMS of them.
+func (a *app) playlistItems(pl youtube.Playlist) []reference {
+	ids := pl.GetVideoIDs()
+	if pl.GetItemCount() > len(ids) {
+		a.log.Warn("Playlist has too many videos, only walking the first ones", "playlist", pl.GetID(), "videos", pl.GetItemCount(), "maxPlaylistItems", a.cfg.Graph.MaxPlaylistItems)
+	}
+
+	items := []reference{}
+	for _, id := range ids {
+		items = append(items, reference{playlist: pl, url: youtube.WatchURL(id), id: id})
+	}
+	return items
+}
+
+// collectReferences returns every reference to a video, every reference to a playlist, and every
+// reference to an external object, in the descriptions of the frontier, in the order they
+// appear, followed by those in the comments selected by COMMENTS, marking each video in the
+// frontier as expanded. The links redirecting to a video or playlist follow the direct links
+// of each description or comment.
+func (a *app) collectReferences(state *crawlState, frontier []youtube.Video) ([]reference, []reference, []reference) {
+	lookups := a.lookupFrontier(state, frontier)
+
+	refs := []reference{}
+	playlistRefs := []reference{}
+	externalRefs := []reference{}
+	for i, video := range frontier {
+		state.expanded[video.GetID()] = true
+		a.log.Debug("Base video", "title", video.GetTitle(), "channel", video.GetChannelTitle(), "depth", state.depth[video.GetID()])
+
+		videoRefs, videoPlaylistRefs := a.linkReferences(state, reference{parent: video}, video.GetLinksFromDescription(), video.GetPlaylistUrlsFromDescription())
+		refs = append(refs, videoRefs...)
+		playlistRefs = append(playlistRefs, videoPlaylistRefs...)
+		videoRefs, videoPlaylistRefs, videoExternalRefs := a.externalReferences(state, reference{parent: video}, video.GetDescription(), lookups[i].resolved)
+		refs = append(refs, videoRefs...)
+		playlistRefs = append(playlistRefs, videoPlaylistRefs...)
+		externalRefs = append(externalRefs, videoExternalRefs...)
+
+		for _, cm := range a.comments(state, video, lookups[i]) {
+			commentRefs, commentPlaylistRefs := a.linkReferences(state, reference{parent: video, comment: true}, cm.GetLinksFromText(), cm.GetPlaylistUrlsFromText())
+			refs = append(refs, commentRefs...)
+			playlistRefs = append(playlistRefs, commentPlaylistRefs...)
+			commentRefs, commentPlaylistRefs, commentExternalRefs := a.externalReferences(state, reference{parent: video, comment: true}, cm.GetText(), lookups[i].resolved)
+			refs = append(refs, commentRefs...)
+			playlistRefs = append(playlistRefs, commentPlaylistRefs...)
+			externalRefs = append(externalRefs, commentExternalRefs...)
+		}
+	}
+	return refs, playlistRefs, externalRefs
+}
+
+// lookupFrontier gets the comments on each video of the frontier selected by COMMENTS, and
+// follows the links outside YouTube in its description and comments if RESOLVE_LINKS is set,
+// using the pool of CRAWL_CONCURRENCY workers. The results are in the order of the frontier,
+// and are recorded in state by collectReferences.
+func (a *app) lookupFrontier(state *crawlState, frontier []youtube.Video) []lookupResult {
+	lookups := make([]lookupResult, len(frontier))
+	withComments := a.cfg.Graph.Comments != CommentsOff && !state.stopped
+	if !withComments && a.resolver == nil {
+		return lookups
+	}
+
+	a.inPool(len(frontier), func(i int) {
+		video := frontier[i]
+		if withComments {
+			lookups[i].comments, lookups[i].commentsErr = youtube.GetComments(a.source, video, youtube.CommentFilter{
+				AllAuthors: a.cfg.Graph.Comments == CommentsAll,
+				MaxPages:   a.cfg.Graph.MaxCommentPages,
+			})
+		}
+		if a.resolver == nil {
+			return
+		}
+
+		lookups[i].resolved = map[string]resolution{}
+		texts := []string{video.GetDescription()}
+		for _, cm := range lookups[i].comments {
+			texts = append(texts, cm.GetText())
+		}
+		for _, text := range texts {
+			for _, link := range youtube.GetExternalLinksFromText(text) {
+				if _, ok := lookups[i].resolved[link.URL]; ok {
+					continue
+				}
+				target, err := a.resolver.Resolve(link.URL)
+				lookups[i].resolved[link.URL] = resolution{target: target, err: err}
+			}
+		}
+	})
+	return lookups
+}
+
+// linkReferences returns a reference to each of the videos in links, and to each of the
+// playlists in playlistURLs, found in the same place as base.
+func (a *app) linkReferences(state *crawlState, base reference, links []youtube.Link, playlistURLs []string) ([]reference, []reference) {
+	refs := []reference{}
+	for _, link := range links {
+		rawURL := link.URL
+		ref := base
+		ref.url = rawURL
+		ref.chapter = link.Chapter
+		ref.context = link.Context()
+
+		url, err := youtube.NewURL(rawURL)
+		if err != nil {
+			a.log.Warn("Unable to get video", "input", rawURL, "error", err)
+			// Without a video ID, the URL itself is the only identifier of the reference
+			state.broken[rawURL] = brokenReference{status: graph.StatusParseError, err: err}
+			ref.id = rawURL
+			ref.parseErr = err
+			refs = append(refs, ref)
+			continue
+		}
+		ref.id = url.GetID()
+		ref.timestamp = url.GetTimestamp()
+		refs = append(refs, ref)
+	}
+
+	playlistRefs := []reference{}
+	for _, rawURL := range playlistURLs {
+		url, err := youtube.NewPlaylistURL(rawURL)
+		if err != nil {
+			a.log.Warn("Unable to get playlist", "input", rawURL, "error", err)
+			continue
+		}
+		ref := base
+		ref.url = rawURL
+		ref.id = url.GetID()
+		playlistRefs = append(playlistRefs, ref)
+	}
+	return refs, playlistRefs
+}
+
+// externalReferences returns a reference to each video, and to each playlist, that the links in
+// text that aren't to YouTube redirect to, according to resolved, along with a reference to the
+// external object each of the other links refers to, found in the same place as base. Links are
+// only followed if RESOLVE_LINKS is set, and only to the hosts in RESOLVE_ALLOWED_HOSTS, while
+// external objects are only referenced if EXTERNAL_LINKS is set.
+func (a *app) externalReferences(state *crawlState, base reference, text string, resolved map[string]resolution) ([]reference, []reference, []reference) {
+	refs := []reference{}
+	playlistRefs := []reference{}
+	externalRefs := []reference{}
+	if a.resolver == nil && !a.cfg.Graph.ExternalLinks {
+		return refs, playlistRefs, externalRefs
+	}
+
+	// Different links to the same external object, such as a paper's DOI and its publisher's
+	// page, are merged into a single edge by the graph
+	for _, link := range youtube.GetExternalLinksFromText(text) {
+		ref := base
+		ref.url = link.URL
+		ref.chapter = link.Chapter
+		ref.context = link.Context()
+
+		if res, ok := resolved[link.URL]; ok {
+			target, err := res.target, res.err
+			switch {
+			case errors.Is(err, resolve.ErrHostNotAllowed):
+				// Links to any other host refer to the object they are written as
+			case err != nil:
+				a.log.Warn("Unable to resolve link", "input", link.URL, "error", err)
+			default:
+				landing, err := youtube.ParseReference(target)
+				if err == nil && (landing.Kind == youtube.KindVideo || landing.Kind == youtube.KindPlaylist) {
+					ref.url = target
+					ref.id = landing.ID
+					ref.timestamp = landing.Timestamp
+					ref.originalURL = link.URL
+					if landing.Kind == youtube.KindPlaylist {
+						playlistRefs = append(playlistRefs, ref)
+					} else {
+						refs = append(refs, ref)
+					}
+					state.resolved++
+					a.log.Debug("Resolved link", "input", link.URL, "target", target)
+					continue
+				}
+				// Links landing anywhere else on YouTube, such as a channel, or on a broken link, aren't references
+				if landing.Kind != "" {
+					a.log.Debug("Link does not redirect to a video or playlist", "input", link.URL, "target", target)
+					continue
+				}
+				// Links landing outside YouTube refer to the object they land on
+				if target != link.URL {
+					ref.url = target
+					ref.originalURL = link.URL
+				}
+			}
+		}
+
+		if !a.cfg.Graph.ExternalLinks {
+			continue
+		}
+		ext, err := external.Parse(ref.url)
+		if err != nil {
+			a.log.Debug("Unable to parse external link", "input", ref.url, "error", err)
+			continue
+		}
+		ref.id = ext.ID
+		ref.external = &ext
+		externalRefs = append(externalRefs, ref)
+	}
+	return refs, playlistRefs, externalRefs
+}
+
+// comments returns the comments on video selected by COMMENTS, up to MAX_COMMENT_PAGES pages, as
+// looked up by lookupFrontier. Failing to get the comments only loses the references in them,
+// unless the quota is spent, in which case the comments on the rest of the frontier are dropped.
+func (a *app) comments(state *crawlState, video youtube.Video, lookup lookupResult) []youtube.Comment {
+	if a.cfg.Graph.Comments == CommentsOff || state.stopped {
+		return []youtube.Comment{}
+	}
+
+	comments, err := lookup.comments, lookup.commentsErr
+	switch {
+	case err == nil:
+		state.comments += len(comments)
+	case youtube.IsQuotaError(err):
+		a.log.Warn("Quota spent, stopping crawl", "video", video.GetID(), "error", err)
+		state.stopped = true
+	case errors.Is(err, youtube.ErrUnsupported):
+		a.log.Debug("Unable to get comments", "video", video.GetID(), "error", err)
+	default:
+		a.log.Warn("Unable to get comments", "video", video.GetID(), "error", err)
+	}
+	return comments
+}
+
+// fetchReferences fetches every video in refs that was not already fetched during this
+// run. The IDs are split into batches of youtube.MaxIDsPerRequest, so that the whole level
+// is resolved in as few calls as possible, and the batches are fetched by a pool of
+// CRAWL_CONCURRENCY workers. Newly fetched videos are recorded at the given depth, in the
+// order they appear in refs, regardless of which worker fetched them.
+func (a *app) fetchReferences(state *crawlState, refs []reference, depth int) {
+	pending := []reference{}
+	queued := map[string]bool{}
+	for _, ref := range refs {
+		if ref.parseErr != nil {
+			continue
+		}
+		if _, ok := state.videos[ref.id]; ok || queued[ref.id] {
+			state.avoided++
+			continue
+		}
+		if _, ok := state.broken[ref.id]; ok {
+			state.avoided++
+			continue
+		}
+		queued[ref.id] = true
+		pending = append(pending, ref)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	batches := [][]string{}
+	for start := 0; start < len(pending); start += youtube.MaxIDsPerRequest {
+		end := start + youtube.MaxIDsPerRequest
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := []string{}
+		for _, ref := range pending[start:end] {
+			batch = append(batch, ref.id)
+		}
+		batches = append(batches, batch)
+	}
+
+	results := make([]fetchResult, len(batches))
+	a.inPool(len(batches), func(i int) {
+		videos, err := a.source.GetVideosByIDs(batches[i])
+		results[i] = fetchResult{ids: batches[i], videos: videos, err: err}
+	})
+
+	fetched := map[string]youtube.Video{}
+	for _, res := range results {
+		var missingErr *youtube.MissingVideosError
+		if youtube.IsQuotaError(res.err) {
+			a.log.Warn("Quota spent, stopping crawl", "ids", res.ids, "error", res.err)
+			state.stopped = true
+		} else if errors.As(res.err, &missingErr) {
+			a.log.Warn("Unable to find videos", "ids", missingErr.IDs)
+			for _, id := range missingErr.IDs {
+				if err, ok := missingErr.Errors[id]; ok {
+					state.broken[id] = brokenReference{status: statusFromError(err), err: err}
+				} else {
+					state.broken[id] = a.missingReference(id, res.err)
+				}
+			}
+		} else if res.err != nil {
+			a.log.Warn("Unable to get videos", "ids", res.ids, "error", res.err)
+			for _, id := range res.ids {
+				if _, ok := res.videos[id]; !ok {
+					state.broken[id] = brokenReference{status: statusFromError(res.err), err: res.err}
+				}
+			}
+		}
+		for id, vid := range res.videos {
+			fetched[id] = vid
+		}
+	}
+	a.log.Debug("Fetched videos", "depth", depth, "requested", len(pending), "found", len(fetched), "batches", len(batches))
+
+	for _, ref := range pending {
+		if vid, ok := fetched[ref.id]; ok {
+			state.add(vid, depth)
+		}
+	}
+}
+
+// inPool calls fn with every index from 0 to n-1 from a pool of CRAWL_CONCURRENCY workers,
+// returning once every call has returned. fn must only write to its own index of a result
+// slice, leaving the crawl state to the main goroutine.
+func (a *app) inPool(n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < a.cfg.Graph.CrawlConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// newVideoNode creates the node representing vid, described by its metadata and its depth in the crawl.
+func newVideoNode(state *crawlState, vid youtube.Video) (graph.Node, error) {
+	return graph.NewVideoNode(vid.GetID(), vid.GetTitle(), graph.VideoMetadata{
+		ChannelID:    vid.GetChannelID(),
+		ChannelTitle: vid.GetChannelTitle(),
+		PublishedAt:  vid.GetPublishedAt(),
+		Duration:     vid.GetDuration(),
+		ViewCount:    vid.GetViewCount(),
+		LikeCount:    vid.GetLikeCount(),
+		ThumbnailURL: vid.GetThumbnailURL(),
+		WatchURL:     vid.GetWatchURL(),
+		Depth:        state.depth[vid.GetID()],
+	})
+}
+
+// newPlaylistNode creates the node representing pl, described by its metadata and its depth in the crawl.
+func newPlaylistNode(state *crawlState, pl youtube.Playlist) (graph.Node, error) {
+	label := pl.GetTitle()
+	if label == "" {
+		label = pl.GetURL()
+	}
+	return graph.NewPlaylistNode(pl.GetID(), label, graph.PlaylistMetadata{
+		ChannelID:    pl.GetChannelID(),
+		ChannelTitle: pl.GetChannelTitle(),
+		ItemCount:    pl.GetItemCount(),
+		URL:          pl.GetURL(),
+		Depth:        state.depth[pl.GetID()],
+	})
+}
+
+// parentNode creates the node representing the video or playlist containing ref.
+func parentNode(state *crawlState, ref reference) (graph.Node, error) {
+	if ref.playlist != nil {
+		return newPlaylistNode(state, ref.playlist)
+	}
+	return newVideoNode(state, ref.parent)
+}
+
+// addBrokenReference adds a placeholder node for a reference that could not be resolved to
+// a video or playlist, along with the edge from parentNode, so that the graph still shows the link.
+func (a *app) addBrokenReference(g graph.Graph, state *crawlState, parentNode graph.Node, ref reference) {
+	broken, ok := state.broken[ref.id]
+	if !ok {
+		// The reference was never fetched, such as when the crawl stopped early
+		return
+	}
+
+	label := broken.label
+	if label == "" {
+		label = ref.url
+	}
+	childNode, err := graph.NewPlaceholderNode(ref.id, label, broken.status, ref.url)
+	if err != nil {
+		a.log.Warn("Unable to create placeholder node", "input", ref.url, "error", err)
+		return
+	}
+
+	addEdge(g, parentNode, childNode, ref)
+	a.log.Debug("Broken reference", "status", broken.status, "url", ref.url, "parent", ref.parentTitle(), "error", broken.err)
+}
+
+// missingReference returns the brokenReference for a video that the client could not find.
+// A video that was previously cached must have existed, and is therefore deleted.
+func (a *app) missingReference(id string, err error) brokenReference {
+	if a.repo != nil {
+		entry, repoErr := a.repo.GetVideo(id)
+		if repoErr == nil || errors.Is(repoErr, repository.ErrExpired) {
+			return brokenReference{status: graph.StatusDeleted, label: entry.Video.GetTitle(), err: err}
+		}
+	}
+	return brokenReference{status: graph.StatusNotFound, err: err}
+}
+
+// statusFromError returns the placeholder node status describing err.
+func statusFromError(err error) string {
+	switch {
+	case errors.Is(err, youtube.ErrVideoDeleted):
+		return graph.StatusDeleted
+	case errors.Is(err, youtube.ErrPrivateVideo):
+		return graph.StatusPrivate
+	case errors.Is(err, youtube.ErrVideoNotFound), errors.Is(err, youtube.ErrPlaylistNotFound):
+		return graph.StatusNotFound
+	case errors.Is(err, youtube.ErrInvalidURL):
+		return graph.StatusParseError
+	}
+	return graph.StatusUnavailable
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"math"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+const (
+	// defaultLinksPerVideo is the number of references assumed for a video that isn't cached,
+	// when there are no cached videos to derive an average from
+	defaultLinksPerVideo = 3.0
+)
+
+// QuotaEstimate is the expected quota cost of a crawl, derived from the cached videos and MAX_DEPTH.
+// It counts the search.list, videos.list and commentThreads.list calls of the crawl only. Playlists
+// are excluded: the playlists.list and playlistItems.list calls of any playlist the crawl
+// references aren't counted, nor are the videos in it. The channels.list and playlistItems.list
+// calls listing the uploads of a channel aren't either, as from-channel has no dry run.
+type QuotaEstimate struct {
+	RootID   string `json:"rootId,omitempty"`
+	MaxDepth int    `json:"maxDepth"`
+	// CachedVideos is the number of videos the crawl is expected to read from the cache for free
+	CachedVideos int `json:"cachedVideos"`
+	// KnownVideos is the number of videos that must be fetched, whose IDs are known from the cache
+	KnownVideos int `json:"knownVideos"`
+	// EstimatedVideos is the number of videos expected beneath videos that aren't cached
+	EstimatedVideos int `json:"estimatedVideos"`
+	// LinksPerVideo is the average number of references assumed for a video that isn't cached
+	LinksPerVideo float64 `json:"linksPerVideo"`
+	// Units is the estimated number of quota units the crawl will spend
+	Units int64 `json:"units"`
+	// Exact is true if every level of the crawl could be resolved from the cache
+	Exact bool `json:"exact"`
+}
+
+func (a *app) EstimateFromURL(rawURL string) (QuotaEstimate, error) {
+	url, err := youtube.NewURL(rawURL)
+	if err != nil {
+		return QuotaEstimate{}, err
+	}
+	return a.estimate(url.GetID(), 0)
+}
+
+func (a *app) EstimateFromTitle(title string) (QuotaEstimate, error) {
+	// The video matching the title can't be known without searching
+	return a.estimate("", youtube.CostSearchList)
+}
+
+func (a *app) EstimateFromID(id string) (QuotaEstimate, error) {
+	url, err := youtube.NewURL(id)
+	if err != nil {
+		return QuotaEstimate{}, err
+	}
+	return a.estimate(url.GetID(), 0)
+}
+
+// estimate walks the cached references of rootID level by level, the same way crawl does,
+// without calling the client. Levels containing videos that aren't cached are estimated
+// using the average number of references per cached video. An empty rootID means the
+// root video itself is unknown.
+func (a *app) estimate(rootID string, units int64) (QuotaEstimate, error) {
+	links, err := a.cachedLinks()
+	if err != nil {
+		return QuotaEstimate{}, err
+	}
+
+	est := QuotaEstimate{
+		RootID:        rootID,
+		MaxDepth:      a.cfg.Graph.MaxDepth,
+		LinksPerVideo: averageLinks(links),
+		Units:         units,
+	}
+
+	// The root video is always fetched on its own
+	level := []string{}
+	unknown := 0.0
+	if rootID == "" {
+		unknown = 1
+		est.EstimatedVideos++
+		est.Units += youtube.CostVideosList
+	} else if _, ok := links[rootID]; ok {
+		level = append(level, rootID)
+		est.CachedVideos++
+	} else {
+		level = append(level, rootID)
+		est.KnownVideos++
+		est.Units += youtube.CostVideosList
+	}
+
+	seen := map[string]bool{rootID: true}
+	for depth := 0; depth <= a.cfg.Graph.MaxDepth && (len(level) > 0 || unknown > 0); depth++ {
+		est.Units += a.commentUnits(float64(len(level)) + unknown)
+
+		next := []string{}
+		nextUnknown := unknown * est.LinksPerVideo
+		for _, id := range level {
+			refs, ok := links[id]
+			if !ok {
+				nextUnknown += est.LinksPerVideo
+				continue
+			}
+			for _, ref := range refs {
+				if !seen[ref] {
+					seen[ref] = true
+					next = append(next, ref)
+				}
+			}
+		}
+
+		misses := 0
+		for _, id := range next {
+			if _, ok := links[id]; ok {
+				est.CachedVideos++
+			} else {
+				misses++
+			}
+		}
+		est.KnownVideos += misses
+		est.EstimatedVideos += int(math.Ceil(nextUnknown))
+
+		fetched := float64(misses) + nextUnknown
+		est.Units += int64(math.Ceil(fetched/youtube.MaxIDsPerRequest)) * youtube.CostVideosList
+
+		level = next
+		unknown = nextUnknown
+	}
+	est.Exact = est.EstimatedVideos == 0
+
+	return est, nil
+}
+
+// commentUnits returns the most quota units spent searching the comments of the given number of
+// videos, as comments aren't cached and every page up to MAX_COMMENT_PAGES may be fetched.
+func (a *app) commentUnits(videos float64) int64 {
+	if a.cfg.Graph.Comments == CommentsOff {
+		return 0
+	}
+	return int64(math.Ceil(videos)) * int64(a.cfg.Graph.MaxCommentPages) * youtube.CostCommentThreadsList
+}
+
+// cachedLinks returns the IDs of the videos referenced by each unexpired cached video, keyed by video ID.
+func (a *app) cachedLinks() (map[string][]string, error) {
+	links := map[string][]string{}
+	if a.repo == nil {
+		return links, nil
+	}
+
+	entries, err := a.repo.Entries()
+	if err != nil {
+		return links, err
+	}
+	for _, entry := range entries {
+		if entry.Expired {
+			continue
+		}
+		ids := []string{}
+		for _, rawURL := range entry.Links {
+			url, err := youtube.NewURL(rawURL)
+			if err != nil {
+				continue
+			}
+			ids = append(ids, url.GetID())
+		}
+		links[entry.Video.GetID()] = ids
+	}
+	return links, nil
+}
+
+// averageLinks returns the average number of references per video in links.
+func averageLinks(links map[string][]string) float64 {
+	if len(links) == 0 {
+		return defaultLinksPerVideo
+	}
+	total := 0
+	for _, refs := range links {
+		total += len(refs)
+	}
+	return float64(total) / float64(len(links))
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+func TestEstimate(t *testing.T) {
+	// Among the cached videos, series00002 references series00001 and series00003, which
+	// reference guest000001 and two videos that aren't cached. They average 2 links each.
+	cached := []string{"series00001", "series00002", "series00003", "guest000001"}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		cached   []string
+		opts     []func(cfg *Config)
+		estimate func(a App) (QuotaEstimate, error)
+		expected QuotaEstimate
+	}{
+		{
+			name:     "cached chain",
+			maxDepth: 1,
+			cached:   cached,
+			estimate: func(a App) (QuotaEstimate, error) { return a.EstimateFromID("series00002") },
+			// Only private0000 and removed0000 are fetched, in a single batch
+			expected: QuotaEstimate{RootID: "series00002", MaxDepth: 1, CachedVideos: 4, KnownVideos: 2, LinksPerVideo: 2, Units: youtube.CostVideosList, Exact: true},
+		},
+		{
+			name:     "uncached root",
+			maxDepth: 1,
+			cached:   cached,
+			estimate: func(a App) (QuotaEstimate, error) {
+				return a.EstimateFromURL("https://www.youtube.com/watch?v=nofixture01")
+			},
+			// The root, then 2 and 4 estimated videos at each level
+			expected: QuotaEstimate{RootID: "nofixture01", MaxDepth: 1, KnownVideos: 1, EstimatedVideos: 6, LinksPerVideo: 2, Units: 3 * youtube.CostVideosList},
+		},
+		{
+			name:     "from title",
+			maxDepth: 0,
+			cached:   cached,
+			estimate: func(a App) (QuotaEstimate, error) { return a.EstimateFromTitle("the finale") },
+			expected: QuotaEstimate{MaxDepth: 0, EstimatedVideos: 3, LinksPerVideo: 2, Units: youtube.CostSearchList + 2*youtube.CostVideosList},
+		},
+		{
+			name:     "comment pages",
+			maxDepth: 1,
+			cached:   cached,
+			opts: []func(cfg *Config){func(cfg *Config) {
+				cfg.Graph.Comments = CommentsAll
+				cfg.Graph.MaxCommentPages = 2
+			}},
+			estimate: func(a App) (QuotaEstimate, error) { return a.EstimateFromID("series00002") },
+			// Every page of comments on the 1 and 2 videos at each level
+			expected: QuotaEstimate{RootID: "series00002", MaxDepth: 1, CachedVideos: 4, KnownVideos: 2, LinksPerVideo: 2, Units: youtube.CostVideosList + 6*youtube.CostCommentThreadsList, Exact: true},
+		},
+		{
+			name:     "empty cache",
+			maxDepth: 0,
+			estimate: func(a App) (QuotaEstimate, error) { return a.EstimateFromID("series00002") },
+			expected: QuotaEstimate{RootID: "series00002", MaxDepth: 0, KnownVideos: 1, EstimatedVideos: 3, LinksPerVideo: defaultLinksPerVideo, Units: 2 * youtube.CostVideosList},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := append([]func(cfg *Config){func(cfg *Config) {
+				cfg.Cache = CacheConfig{Enabled: true, Dir: dir}
+			}}, tt.opts...)
+			a := newFixtureApp(t, tt.maxDepth, opts...)
+
+			// Fetching the videos through the app caches them
+			if len(tt.cached) > 0 {
+				_, err := a.(*app).source.GetVideosByIDs(tt.cached)
+				require.NoError(t, err, "unable to cache the fixtures")
+			}
+
+			est, err := tt.estimate(a)
+			require.NoError(t, err, "estimate produced an unexpected error")
+			require.Equal(t, tt.expected, est)
+		})
+	}
+}
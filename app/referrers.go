@@ -0,0 +1,125 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/internal/repository"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+var errNoRepository = errors.New("the referrers of a video are looked up in the description links of the cached videos, which requires CACHE_ENABLED or SOURCE=cache")
+
+// GraphOfReferrers creates the upstream graph of the video with the given id, which may also be
+// a URL, made of the cached videos whose descriptions link to it, the cached videos linking to
+// those, and so on up to depth levels away. Only the root video itself may be fetched, if it
+// isn't cached. Only links written in descriptions as links to a video are followed, as the cache
+// holds neither the comments of videos nor where shortened links redirect to, so unlike the
+// downstream graph, the upstream graph has no edges for those.
+func (a *app) GraphOfReferrers(id string, depth int) (graph.Graph, error) {
+	defer a.logQuota()
+
+	if depth < 0 || depth > enforcedMaximumDepth {
+		return nil, fmt.Errorf("provided depth (%d) invalid; Must be between 0 and %d", depth, enforcedMaximumDepth)
+	}
+	if a.repo == nil {
+		return nil, errNoRepository
+	}
+	url, err := youtube.NewURL(id)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := repository.LoadReferrerIndex(a.repo)
+	if err != nil {
+		return nil, err
+	}
+	root, err := a.cachedVideo(url.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	a.log.Info("Generating referrers graph for Video", "title", root.GetTitle(), "channel", root.GetChannelTitle(), "indexedVideos", idx.Len())
+	g := graph.NewGraph("", "Youtube Video Dependencies", "ydg")
+	state := newCrawlState()
+	state.add(root, 0)
+	rootNode, err := newVideoNode(state, root)
+	if err == nil {
+		g.AddNode(rootNode)
+	}
+
+	frontier := []youtube.Video{root}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		next := []youtube.Video{}
+		for _, vid := range frontier {
+			for _, referrer := range idx.Referrers(vid.GetID()) {
+				if _, ok := state.videos[referrer.Video.GetID()]; !ok {
+					state.add(referrer.Video, level)
+					next = append(next, referrer.Video)
+				}
+				a.addReferrerEdge(g, state, vid, referrer)
+			}
+		}
+		frontier = next
+	}
+
+	a.log.Info("Referrers found", "videos", len(state.videos)-1, "depth", depth)
+	return g, nil
+}
+
+// cachedVideo returns the video with the given id from the cache, even if it has expired,
+// falling back to the source if it isn't cached.
+func (a *app) cachedVideo(id string) (youtube.Video, error) {
+	entry, err := a.repo.GetVideo(id)
+	if err == nil || errors.Is(err, repository.ErrExpired) {
+		return entry.Video, nil
+	}
+	return a.source.GetVideoByID(id)
+}
+
+// addReverseEdges adds an edge to each video seen during the crawl from every other video seen
+// whose description links to it but whose references were never fetched, such as the videos at
+// MAX_DEPTH or those left when the quota was spent, so that the references between the videos
+// of the graph are complete.
+func (a *app) addReverseEdges(g graph.Graph, state *crawlState) {
+	videos := []youtube.Video{}
+	for _, id := range state.order {
+		videos = append(videos, state.videos[id])
+	}
+	idx := repository.NewReferrerIndex(videos...)
+
+	for _, id := range state.order {
+		for _, referrer := range idx.Referrers(id) {
+			if state.walked[referrer.Video.GetID()] {
+				continue
+			}
+			a.addReferrerEdge(g, state, state.videos[id], referrer)
+			state.reverse++
+		}
+	}
+}
+
+// addReferrerEdge adds the edge from the video of referrer to vid, described by its link.
+func (a *app) addReferrerEdge(g graph.Graph, state *crawlState, vid youtube.Video, referrer repository.Referrer) {
+	parentNode, err := newVideoNode(state, referrer.Video)
+	if err != nil {
+		a.log.Warn("Unable to create new node from referrer", "input", referrer.Video.GetID(), "error", err)
+		return
+	}
+	childNode, err := newVideoNode(state, vid)
+	if err != nil {
+		a.log.Warn("Unable to create new node from referenced video", "input", vid.GetID(), "error", err)
+		return
+	}
+
+	addEdge(g, parentNode, childNode, reference{
+		parent:    referrer.Video,
+		url:       referrer.Link.URL,
+		id:        vid.GetID(),
+		timestamp: referrer.URL.GetTimestamp(),
+		chapter:   referrer.Link.Chapter,
+		context:   referrer.Link.Context(),
+	})
+	a.log.Debug("Referrer", "title", referrer.Video.GetTitle(), "url", referrer.Link.URL, "referenced", vid.GetTitle())
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/internal/repository"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/recorder"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/scrape"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// Sources of videos that can be listed in SOURCE.
+const (
+	SourceAPI     = "api"
+	SourceScrape  = "scrape"
+	SourceCache   = "cache"
+	SourceFixture = "fixture"
+)
+
+// scrapeTimeout is the timeout of each watch page request made by the scrape source.
+const scrapeTimeout = 30 * time.Second
+
+var validSources = []string{SourceAPI, SourceScrape, SourceCache, SourceFixture}
+
+// newSource creates the youtube.Source described by cfg.Youtube.Source. When more than one
+// source is listed, each lookup falls back to the next source if the previous one fails.
+// If the cache is enabled, every video fetched by the sources is stored in repo. Unless
+// RECORD_MODE is off, the HTTP responses of the api and scrape sources are recorded to, or
+// replayed from, the cassettes in CASSETTE_DIR.
+func newSource(cfg Config, repo repository.VideoRepository, quota *youtube.QuotaTracker, log log15.Logger) (youtube.Source, error) {
+	onlyCache := len(cfg.Youtube.Source) == 1 && cfg.Youtube.UsesSource(SourceCache)
+
+	rec, err := recorder.New(cfg.Youtube.Record.Mode, cfg.Youtube.Record.Dir, recorder.WithSecrets(cfg.Youtube.APIKey))
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []youtube.NamedSource{}
+	for _, name := range cfg.Youtube.Source {
+		var src youtube.Source
+		switch name {
+		case SourceAPI:
+			retry := youtube.RetryPolicy{
+				MaxAttempts: cfg.Youtube.Retry.MaxAttempts,
+				BaseDelay:   cfg.Youtube.Retry.BaseDelay,
+				MaxDelay:    cfg.Youtube.Retry.MaxDelay,
+			}
+			opts := []youtube.ClientOption{youtube.WithQuotaTracker(quota), youtube.WithRetryPolicy(retry)}
+			if rec.Mode() != recorder.ModeOff {
+				opts = append(opts, youtube.WithTransport(rec))
+			}
+			client, err := youtube.NewClient(cfg.Youtube.APIKey, log, opts...)
+			if err != nil {
+				return nil, err
+			}
+			src = client
+		case SourceScrape:
+			opts := []scrape.Option{}
+			if rec.Mode() != recorder.ModeOff {
+				opts = append(opts, scrape.WithHTTPClient(&http.Client{Transport: rec, Timeout: scrapeTimeout}))
+			}
+			src = scrape.NewSource(scrape.New(opts...))
+		case SourceCache:
+			// Expired videos are only served when the cache is the sole source, as
+			// otherwise the following sources are able to refresh them
+			src = repository.NewSource(repo, onlyCache)
+		case SourceFixture:
+			client, err := youtube.NewFixtureClient(cfg.Youtube.FixturesDir, youtube.WithFixtureQuotaTracker(quota))
+			if err != nil {
+				return nil, err
+			}
+			src = client
+		default:
+			return nil, fmt.Errorf("unsupported source %s", name)
+		}
+		sources = append(sources, youtube.NamedSource{Name: name, Source: src})
+	}
+
+	var src youtube.Source
+	if len(sources) == 1 {
+		src = sources[0].Source
+	} else {
+		src, err = youtube.NewFallbackSource(log, sources...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Cache.Enabled && !onlyCache {
+		src = repository.NewCachedSource(src, repo, log)
+	}
+	return src, nil
+}
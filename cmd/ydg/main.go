@@ -1,31 +1,64 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/inconshreveable/log15"
 	"github.com/urfave/cli/v2"
 
 	"github.com/TrevorEdris/youtube-dependency-graph/app"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
 )
 
 const (
 	appName        = "ydg"
 	defaultVersion = "v0.0.0"
 
+	dateLayout = "2006-01-02"
+
 	flagURL   = "url"
 	flagTitle = "title"
 	flagID    = "id"
+
+	flagPlaylist = "playlist"
+
+	flagChannel         = "channel"
+	flagPublishedAfter  = "published-after"
+	flagPublishedBefore = "published-before"
+	flagMaxVideos       = "max-videos"
+
+	flagMaxQuota = "max-quota"
+	flagDryRun   = "dry-run"
+	flagComments = "comments"
+	flagResolve  = "resolve-links"
+	flagExternal = "external-links"
+
+	flagDepth = "depth"
+
+	flagExpiredOnly = "expired-only"
+
+	flagFixtures = "fixtures"
 )
 
 var (
 	cfg app.Config
 
 	appVersion = getVersion()
-	log        = log15.New("module", appName)
+	log        = newLogger()
 )
 
+// newLogger creates the application logger, writing to stderr so that stdout only ever
+// contains the output of a command.
+func newLogger() log15.Logger {
+	l := log15.New("module", appName)
+	l.SetHandler(log15.StreamHandler(os.Stderr, log15.LogfmtFormat()))
+	return l
+}
+
 func getVersion() string {
 	v := os.Getenv("VERSION")
 	if v == "" {
@@ -58,13 +91,21 @@ func initLogging(logLevel, logFmt string) {
 	log.SetHandler(
 		log15.LvlFilterHandler(
 			lvl,
-			log15.StreamHandler(os.Stdout, loggerFormat),
+			log15.StreamHandler(os.Stderr, loggerFormat),
 		),
 	)
 }
 
 func initSettings(c *cli.Context) error {
 	var err error
+	if c.IsSet(flagFixtures) {
+		// Applied through the environment so that ParseConfig validates the resulting config.
+		// Fixtures are never written to the cache, as they aren't real videos
+		os.Setenv("SOURCE", app.SourceFixture)
+		os.Setenv("FIXTURES_DIR", c.String(flagFixtures))
+		os.Setenv("CACHE_ENABLED", "false")
+	}
+
 	cfg, err = app.ParseConfig()
 	if err != nil {
 		log.Error("Unable to parse config", "error", err)
@@ -75,48 +116,372 @@ func initSettings(c *cli.Context) error {
 	return nil
 }
 
+// newGraphApp creates the ydg app, applying any flags shared by the graph commands to cfg.
+func newGraphApp(c *cli.Context) (app.App, error) {
+	err := applyCrawlFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	err = applyOutputFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	return app.New(cfg, log)
+}
+
+// applyCrawlFlags applies the flags controlling how the graph is crawled to cfg.
+func applyCrawlFlags(c *cli.Context) error {
+	if c.IsSet(flagMaxQuota) {
+		cfg.Youtube.MaxQuota = c.Int64(flagMaxQuota)
+		err := cfg.Youtube.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if c.IsSet(flagComments) {
+		cfg.Graph.Comments = strings.ToLower(strings.TrimSpace(c.String(flagComments)))
+		err := cfg.Graph.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if c.IsSet(flagExternal) {
+		cfg.Graph.ExternalLinks = c.Bool(flagExternal)
+	}
+	if c.IsSet(flagResolve) {
+		cfg.Graph.Resolve.Enabled = c.Bool(flagResolve)
+		err := cfg.Graph.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// graphFlags returns the flags shared by every command that creates a graph, following flags.
+func graphFlags(flags ...cli.Flag) []cli.Flag {
+	flags = append(flags, outputFlags()...)
+	return crawlFlags(flags...)
+}
+
+// crawlFlags returns the flags controlling how the graph is crawled, following flags.
+func crawlFlags(flags ...cli.Flag) []cli.Flag {
+	return append(flags,
+		&cli.Int64Flag{
+			Name:  flagMaxQuota,
+			Usage: "The maximum number of quota units to spend, emitting a partial graph once spent (0 is unlimited, overrides MAX_QUOTA)",
+			Value: 0,
+		},
+		&cli.StringFlag{
+			Name:  flagComments,
+			Usage: "Whose top-level comments to search for references, in addition to the description (off, channel, all; overrides COMMENTS)",
+		},
+		&cli.BoolFlag{
+			Name:  flagResolve,
+			Usage: "Follow shortened and redirecting links to the videos and playlists they land on (overrides RESOLVE_LINKS)",
+		},
+		&cli.BoolFlag{
+			Name:  flagExternal,
+			Usage: "Keep links that aren't to YouTube, such as papers, as external nodes (overrides EXTERNAL_LINKS)",
+		},
+	)
+}
+
+// estimatedGraphFlags returns the flags of a command creating a graph whose cost can be
+// estimated from a single root video, following flags.
+func estimatedGraphFlags(flags ...cli.Flag) []cli.Flag {
+	return append(graphFlags(flags...),
+		&cli.BoolFlag{
+			Name:  flagDryRun,
+			Usage: "Print an estimate of the quota units the graph would cost, based on the cache and MAX_DEPTH, without calling the API",
+			Value: false,
+		},
+	)
+}
+
 func cliCreateGraphFromURL(c *cli.Context) error {
-	ydg, err := app.New(cfg, log)
+	ydg, err := newGraphApp(c)
 	if err != nil {
 		log.Error("Unable to create ydg app", "error", err)
 		return err
 	}
 
-	err = ydg.GraphFromURL(c.String(flagURL))
+	if c.Bool(flagDryRun) {
+		est, err := ydg.EstimateFromURL(c.String(flagURL))
+		if err != nil {
+			log.Error("ydg estimate failed", "error", err)
+			return err
+		}
+		return writeJSON(est)
+	}
+
+	g, err := ydg.GraphFromURL(c.String(flagURL))
 	if err != nil {
 		log.Error("ydg execution failed", "error", err)
 		return err
 	}
-	return nil
+	return writeGraph(g)
 }
 
 func cliCreateGraphFromTitle(c *cli.Context) error {
-	ydg, err := app.New(cfg, log)
+	ydg, err := newGraphApp(c)
 	if err != nil {
 		log.Error("Unable to create ydg app", "error", err)
 		return err
 	}
 
-	err = ydg.GraphFromTitle(c.String(flagTitle))
+	if c.Bool(flagDryRun) {
+		est, err := ydg.EstimateFromTitle(c.String(flagTitle))
+		if err != nil {
+			log.Error("ydg estimate failed", "error", err)
+			return err
+		}
+		return writeJSON(est)
+	}
+
+	g, err := ydg.GraphFromTitle(c.String(flagTitle))
 	if err != nil {
 		log.Error("ydg execution failed", "error", err)
 		return err
 	}
-	return nil
+	return writeGraph(g)
 }
 
 func cliCreateGraphFromID(c *cli.Context) error {
+	ydg, err := newGraphApp(c)
+	if err != nil {
+		log.Error("Unable to create ydg app", "error", err)
+		return err
+	}
+
+	if c.Bool(flagDryRun) {
+		est, err := ydg.EstimateFromID(c.String(flagID))
+		if err != nil {
+			log.Error("ydg estimate failed", "error", err)
+			return err
+		}
+		return writeJSON(est)
+	}
+
+	g, err := ydg.GraphFromID(c.String(flagID))
+	if err != nil {
+		log.Error("ydg execution failed", "error", err)
+		return err
+	}
+	return writeGraph(g)
+}
+
+func cliCreateGraphFromPlaylist(c *cli.Context) error {
+	ydg, err := newGraphApp(c)
+	if err != nil {
+		log.Error("Unable to create ydg app", "error", err)
+		return err
+	}
+
+	g, err := ydg.GraphFromPlaylist(c.String(flagPlaylist))
+	if err != nil {
+		log.Error("ydg execution failed", "error", err)
+		return err
+	}
+	return writeGraph(g)
+}
+
+func cliCreateGraphFromChannel(c *cli.Context) error {
+	filter, err := uploadsFilter(c)
+	if err != nil {
+		log.Error("Invalid uploads filter", "error", err)
+		return err
+	}
+
+	ydg, err := newGraphApp(c)
+	if err != nil {
+		log.Error("Unable to create ydg app", "error", err)
+		return err
+	}
+
+	g, err := ydg.GraphFromChannel(c.String(flagChannel), filter)
+	if err != nil {
+		log.Error("ydg execution failed", "error", err)
+		return err
+	}
+	return writeGraph(g)
+}
+
+func cliCreateGraphOfReferrers(c *cli.Context) error {
+	ydg, err := newGraphApp(c)
+	if err != nil {
+		log.Error("Unable to create ydg app", "error", err)
+		return err
+	}
+
+	depth := cfg.Graph.MaxDepth
+	if c.IsSet(flagDepth) {
+		depth = c.Int(flagDepth)
+	}
+	g, err := ydg.GraphOfReferrers(c.String(flagID), depth)
+	if err != nil {
+		log.Error("ydg execution failed", "error", err)
+		return err
+	}
+	return writeGraph(g)
+}
+
+func cliWatchOrder(c *cli.Context) error {
+	// The format is checked before the crawl, so that no quota is spent on an order that can't be written
+	format := c.String(flagFormat)
+	_, err := graph.EncodeWatchOrder(graph.WatchOrder{}, format)
+	if err != nil {
+		log.Error("Invalid watch order format", "error", err)
+		return err
+	}
+
+	err = applyCrawlFlags(c)
+	if err != nil {
+		log.Error("Unable to create ydg app", "error", err)
+		return err
+	}
+	if c.IsSet(flagOutput) {
+		cfg.Output.Path = c.String(flagOutput)
+	}
 	ydg, err := app.New(cfg, log)
 	if err != nil {
 		log.Error("Unable to create ydg app", "error", err)
 		return err
 	}
 
-	err = ydg.GraphFromID(c.String(flagID))
+	g, err := graphFromSource(c, ydg)
 	if err != nil {
 		log.Error("ydg execution failed", "error", err)
 		return err
 	}
+
+	order := graph.NewWatchOrder(g)
+	log.Info("Watch order sorted", "videos", len(order.Items), "cycles", order.Cycles, "totalSeconds", order.TotalSeconds)
+	out, err := graph.EncodeWatchOrder(order, format)
+	if err != nil {
+		log.Error("Unable to encode watch order", "format", format, "error", err)
+		return err
+	}
+	return writeOutput(out)
+}
+
+// graphFromSource creates the graph beginning with whichever one of the --url, --id, --title
+// and --playlist flags is set.
+func graphFromSource(c *cli.Context, ydg app.App) (graph.Graph, error) {
+	sources := []string{flagURL, flagID, flagTitle, flagPlaylist}
+	set := []string{}
+	for _, source := range sources {
+		if c.IsSet(source) {
+			set = append(set, source)
+		}
+	}
+	if len(set) != 1 {
+		return nil, fmt.Errorf("exactly one of --%s must be provided", strings.Join(sources, ", --"))
+	}
+
+	switch set[0] {
+	case flagURL:
+		return ydg.GraphFromURL(c.String(flagURL))
+	case flagID:
+		return ydg.GraphFromID(c.String(flagID))
+	case flagTitle:
+		return ydg.GraphFromTitle(c.String(flagTitle))
+	}
+	return ydg.GraphFromPlaylist(c.String(flagPlaylist))
+}
+
+// uploadsFilter returns the filter described by the flags of the from-channel command.
+func uploadsFilter(c *cli.Context) (youtube.UploadsFilter, error) {
+	filter := youtube.UploadsFilter{MaxVideos: c.Int(flagMaxVideos)}
+	if filter.MaxVideos < 0 {
+		return filter, fmt.Errorf("provided --%s (%d) invalid; Must not be negative", flagMaxVideos, filter.MaxVideos)
+	}
+
+	var err error
+	filter.PublishedAfter, err = parseDate(c.String(flagPublishedAfter))
+	if err != nil {
+		return filter, fmt.Errorf("provided --%s invalid: %s", flagPublishedAfter, err)
+	}
+	filter.PublishedBefore, err = parseDate(c.String(flagPublishedBefore))
+	if err != nil {
+		return filter, fmt.Errorf("provided --%s invalid: %s", flagPublishedBefore, err)
+	}
+	return filter, nil
+}
+
+// parseDate parses s as either a date, such as 2021-10-13, or an RFC 3339 time. An empty s is the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func cliCacheStats(c *cli.Context) error {
+	err := applyOutputFlags(c)
+	if err != nil {
+		return err
+	}
+
+	repo, err := app.NewRepository(cfg)
+	if err != nil {
+		log.Error("Unable to open cache", "error", err)
+		return err
+	}
+
+	stats, err := repo.Stats()
+	if err != nil {
+		log.Error("Unable to read cache stats", "error", err)
+		return err
+	}
+
+	return writeJSON(stats)
+}
+
+func cliCachePurge(c *cli.Context) error {
+	repo, err := app.NewRepository(cfg)
+	if err != nil {
+		log.Error("Unable to open cache", "error", err)
+		return err
+	}
+
+	removed, err := repo.Purge(c.Bool(flagExpiredOnly))
+	if err != nil {
+		log.Error("Unable to purge cache", "error", err)
+		return err
+	}
+
+	log.Info("Purged cache", "dir", cfg.Cache.Dir, "removed", removed)
+	return nil
+}
+
+func cliCacheExport(c *cli.Context) error {
+	err := applyOutputFlags(c)
+	if err != nil {
+		return err
+	}
+
+	repo, err := app.NewRepository(cfg)
+	if err != nil {
+		log.Error("Unable to open cache", "error", err)
+		return err
+	}
+
+	w, err := openOutput()
+	if err != nil {
+		log.Error("Unable to open output", "error", err)
+		return err
+	}
+	defer w.Close()
+
+	err = repo.Export(w)
+	if err != nil {
+		log.Error("Unable to export cache", "error", err)
+		return err
+	}
 	return nil
 }
 
@@ -125,44 +490,168 @@ func newApplication() *cli.App {
 	application.Name = appName
 	application.Version = appVersion
 	application.Before = initSettings
+	application.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:  flagFixtures,
+			Usage: "Read videos from the JSON fixtures in this directory instead of YouTube (sets SOURCE=fixture and disables the cache)",
+			Value: "",
+		},
+	}
 	application.Commands = []*cli.Command{
 		{
 			Name:   "from-url",
 			Usage:  "Create a dependency graph from a URL",
 			Action: cliCreateGraphFromURL,
-			Flags: []cli.Flag{
+			Flags: estimatedGraphFlags(
 				&cli.StringFlag{
 					Name:     flagURL,
 					Usage:    "The URL of the youtube video to begin the graph with",
 					Value:    "",
 					Required: true,
 				},
-			},
+			),
 		},
 		{
 			Name:   "from-title",
 			Usage:  "Create a dependency graph from a video title",
 			Action: cliCreateGraphFromTitle,
-			Flags: []cli.Flag{
+			Flags: estimatedGraphFlags(
 				&cli.StringFlag{
 					Name:     flagTitle,
 					Usage:    "The title of the youtube video to begin the graph with",
 					Value:    "",
 					Required: true,
 				},
-			},
+			),
 		},
 		{
 			Name:   "from-id",
 			Usage:  "Create a dependency graph from a video title",
 			Action: cliCreateGraphFromID,
-			Flags: []cli.Flag{
+			Flags: estimatedGraphFlags(
 				&cli.StringFlag{
 					Name:     flagID,
 					Usage:    "The id of the youtube video to begin the graph with",
 					Value:    "",
 					Required: true,
 				},
+			),
+		},
+		{
+			Name:   "from-playlist",
+			Usage:  "Create a dependency graph from every video in a playlist",
+			Action: cliCreateGraphFromPlaylist,
+			Flags: graphFlags(
+				&cli.StringFlag{
+					Name:     flagPlaylist,
+					Usage:    "The URL or ID of the youtube playlist to begin the graph with",
+					Value:    "",
+					Required: true,
+				},
+			),
+		},
+		{
+			Name:   "from-channel",
+			Usage:  "Create a dependency graph from every video uploaded to a channel",
+			Action: cliCreateGraphFromChannel,
+			Flags: graphFlags(
+				&cli.StringFlag{
+					Name:     flagChannel,
+					Usage:    "The ID, @handle or URL of the youtube channel whose uploads begin the graph",
+					Value:    "",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  flagPublishedAfter,
+					Usage: "Only include uploads published at or after this date (YYYY-MM-DD or RFC 3339)",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  flagPublishedBefore,
+					Usage: "Only include uploads published before this date (YYYY-MM-DD or RFC 3339)",
+					Value: "",
+				},
+				&cli.IntFlag{
+					Name:  flagMaxVideos,
+					Usage: "The maximum number of uploads to include, the most recent first (0 is unlimited)",
+					Value: 100,
+				},
+			),
+		},
+		{
+			Name:   "referrers",
+			Usage:  "Create the upstream graph of the cached videos whose descriptions link to a video",
+			Action: cliCreateGraphOfReferrers,
+			Flags: append(outputFlags(),
+				&cli.StringFlag{
+					Name:     flagID,
+					Usage:    "The id or URL of the youtube video whose referrers to graph",
+					Value:    "",
+					Required: true,
+				},
+				&cli.IntFlag{
+					Name:  flagDepth,
+					Usage: "The number of levels of referrers to include (defaults to MAX_DEPTH)",
+				},
+			),
+		},
+		{
+			Name:   "watch-order",
+			Usage:  "List the videos of a dependency graph in the order to watch them",
+			Action: cliWatchOrder,
+			Flags: crawlFlags(
+				&cli.StringFlag{
+					Name:  flagURL,
+					Usage: "The URL of the youtube video to begin the graph with",
+				},
+				&cli.StringFlag{
+					Name:  flagID,
+					Usage: "The id of the youtube video to begin the graph with",
+				},
+				&cli.StringFlag{
+					Name:  flagTitle,
+					Usage: "The title of the youtube video to begin the graph with",
+				},
+				&cli.StringFlag{
+					Name:  flagPlaylist,
+					Usage: "The URL or ID of the youtube playlist to begin the graph with",
+				},
+				&cli.StringFlag{
+					Name:  flagFormat,
+					Usage: fmt.Sprintf("The output format of the watch order (%s)", strings.Join(graph.WatchFormats, ", ")),
+					Value: graph.WatchFormatText,
+				},
+				outputPathFlag(),
+			),
+		},
+		{
+			Name:  "cache",
+			Usage: "Inspect or manage the local video cache",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "stats",
+					Usage:  "Print a summary of the cached videos",
+					Action: cliCacheStats,
+					Flags:  []cli.Flag{outputPathFlag()},
+				},
+				{
+					Name:   "purge",
+					Usage:  "Remove cached videos",
+					Action: cliCachePurge,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  flagExpiredOnly,
+							Usage: "Only remove videos whose CACHE_TTL has elapsed",
+							Value: false,
+						},
+					},
+				},
+				{
+					Name:   "export",
+					Usage:  "Print every cached video as JSON",
+					Action: cliCacheExport,
+					Flags:  []cli.Flag{outputPathFlag()},
+				},
 			},
 		},
 	}
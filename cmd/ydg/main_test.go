@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runMainEnv is set when the test binary is run as ydg by runYdg.
+const runMainEnv = "YDG_TEST_RUN_MAIN"
+
+var fixturesDir = filepath.Join("..", "..", "app", "testdata", "fixtures")
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runYdg runs ydg with args in a separate process, logging at debug level, and returns what it
+// wrote to stdout and stderr.
+func runYdg(t *testing.T, args ...string) (string, string) {
+	cmd := exec.Command(os.Args[0], args...)
+	// The output of the command is controlled by its flags alone
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "OUTPUT_") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, runMainEnv+"=1", "LOG_LEVEL=debug", "LOG_FMT=logfmt")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	require.NoError(t, cmd.Run(), "ydg %s failed: %s", strings.Join(args, " "), stderr.String())
+	return stdout.String(), stderr.String()
+}
+
+// requireNoLogs fails if out contains a log line.
+func requireNoLogs(t *testing.T, out string) {
+	require.NotContains(t, out, "lvl=", "expected no logs in the output")
+	require.NotContains(t, out, "msg=", "expected no logs in the output")
+}
+
+func TestMain_OutputOnStdout(t *testing.T) {
+	tests := []struct {
+		format string
+		valid  func(t *testing.T, out string)
+	}{
+		{format: "jgf", valid: requireJSON},
+		{format: "custom-json", valid: requireJSON},
+		{format: "dot", valid: func(t *testing.T, out string) {
+			require.True(t, strings.HasPrefix(out, "digraph "), "expected a digraph, got %s", out)
+			require.True(t, strings.HasSuffix(strings.TrimSpace(out), "}"), "expected the digraph to end the output, got %s", out)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stdout, stderr := runYdg(t, "--fixtures", fixturesDir, "from-id", "--id", "series00003", "--format", tt.format)
+			requireNoLogs(t, stdout)
+			tt.valid(t, stdout)
+			require.Contains(t, stderr, `msg="Generating graph for Video"`, "expected the logs on stderr")
+		})
+	}
+}
+
+func TestMain_OutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	stdout, stderr := runYdg(t, "--fixtures", fixturesDir, "from-id", "--id", "series00003", "--format", "jgf", "--output", path)
+	require.Empty(t, stdout, "expected nothing on stdout when writing to a file")
+	require.Contains(t, stderr, `msg="Output written"`)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "unable to read the output file")
+	requireNoLogs(t, string(b))
+	requireJSON(t, string(b))
+}
+
+func TestMain_MaxQuota(t *testing.T) {
+	stdout, stderr := runYdg(t, "--fixtures", fixturesDir, "from-id", "--id", "series00003", "--max-quota", "2")
+	requireJSON(t, stdout)
+	require.Contains(t, stderr, `msg="Quota spent before the crawl completed, the graph is partial"`)
+	require.Contains(t, stderr, `units=2 budget=2`, "expected the fixtures to spend quota like the Data API")
+}
+
+func requireJSON(t *testing.T, out string) {
+	var v map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &v), "expected the output to be JSON, got %s", out)
+	require.NotEmpty(t, v["nodes"], "expected the graph to have nodes")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/graph"
+)
+
+const (
+	flagOutput    = "output"
+	flagFormat    = "format"
+	flagAggregate = "aggregate"
+
+	// stdoutPath is the OUTPUT_PATH that writes to stdout, in addition to an empty path
+	stdoutPath = "-"
+)
+
+// outputFlags returns the flags controlling where and how a graph is written.
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		outputPathFlag(),
+		&cli.StringFlag{
+			Name:  flagFormat,
+			Usage: fmt.Sprintf("The output format of the graph (%s, overrides OUTPUT_FORMAT)", strings.Join(graph.Formats, ", ")),
+		},
+		&cli.StringFlag{
+			Name:  flagAggregate,
+			Usage: fmt.Sprintf("Write a projection of the graph instead of the graph of videos (%s, overrides OUTPUT_AGGREGATE)", strings.Join(graph.Aggregations, ", ")),
+		},
+	}
+}
+
+// outputPathFlag returns the flag controlling where the output of a command is written.
+func outputPathFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    flagOutput,
+		Aliases: []string{"o"},
+		Usage:   "The file to write the output to, or - for stdout (overrides OUTPUT_PATH)",
+	}
+}
+
+// applyOutputFlags applies the output flags set on the command to cfg.Output.
+func applyOutputFlags(c *cli.Context) error {
+	if c.IsSet(flagOutput) {
+		cfg.Output.Path = c.String(flagOutput)
+	}
+	if c.IsSet(flagFormat) {
+		cfg.Output.Format = c.String(flagFormat)
+	}
+	if c.IsSet(flagAggregate) {
+		cfg.Output.Aggregate = c.String(flagAggregate)
+	}
+	return cfg.Output.Validate()
+}
+
+// openOutput returns the writer for cfg.Output.Path, which is stdout if the path is empty or -.
+func openOutput() (io.WriteCloser, error) {
+	path := strings.TrimSpace(cfg.Output.Path)
+	if path == "" || path == stdoutPath {
+		return nopCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create output file %s: %s", path, err)
+	}
+	return f, nil
+}
+
+// writeOutput writes out, followed by a newline, to cfg.Output.Path.
+func writeOutput(out string) error {
+	w, err := openOutput()
+	if err != nil {
+		log.Error("Unable to open output", "error", err)
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, out)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error("Unable to write output", "path", cfg.Output.Path, "error", err)
+		return err
+	}
+	if cfg.Output.Path != "" && cfg.Output.Path != stdoutPath {
+		log.Info("Output written", "path", cfg.Output.Path)
+	}
+	return nil
+}
+
+// writeGraph writes g, aggregated according to cfg.Output.Aggregate, to cfg.Output.Path in
+// the format cfg.Output.Format.
+func writeGraph(g graph.Graph) error {
+	g, err := graph.Aggregate(g, cfg.Output.Aggregate)
+	if err != nil {
+		log.Error("Unable to aggregate graph", "aggregate", cfg.Output.Aggregate, "error", err)
+		return err
+	}
+
+	out, err := graph.Encode(g, cfg.Output.Format)
+	if err != nil {
+		log.Error("Unable to encode graph", "format", cfg.Output.Format, "error", err)
+		return err
+	}
+	return writeOutput(out)
+}
+
+// writeJSON writes the JSON representation of v to cfg.Output.Path.
+func writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeOutput(string(b))
+}
+
+// nopCloser prevents stdout from being closed once a command has written its output.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/inconshreveable/log15"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// cachedSource is a youtube.Source that serves videos from a VideoRepository when possible,
+// only calling the underlying source for videos that are missing or expired.
+type cachedSource struct {
+	client youtube.Source
+	repo   VideoRepository
+	log    log15.Logger
+}
+
+// NewCachedSource wraps src, so that every video it fetches is stored in repo and every
+// video already stored in repo is returned without calling src.
+func NewCachedSource(src youtube.Source, repo VideoRepository, log log15.Logger) youtube.Source {
+	return &cachedSource{
+		client: src,
+		repo:   repo,
+		log:    log,
+	}
+}
+
+func (c *cachedSource) GetVideoByTitle(title string) (youtube.Video, error) {
+	// Searches can't be answered from the repository, but the result can still be stored
+	vid, err := c.client.GetVideoByTitle(title)
+	if err != nil {
+		return vid, err
+	}
+	c.put(vid)
+	return vid, nil
+}
+
+func (c *cachedSource) GetVideoByURL(rawURL string) (youtube.Video, error) {
+	url, err := youtube.NewURL(rawURL)
+	if err == nil {
+		if vid, ok := c.get(url.GetID()); ok {
+			return vid, nil
+		}
+	}
+
+	vid, err := c.client.GetVideoByURL(rawURL)
+	if err != nil {
+		return vid, err
+	}
+	c.put(vid)
+	return vid, nil
+}
+
+func (c *cachedSource) GetVideoByID(id string) (youtube.Video, error) {
+	if vid, ok := c.get(id); ok {
+		return vid, nil
+	}
+
+	vid, err := c.client.GetVideoByID(id)
+	if err != nil {
+		return vid, err
+	}
+	c.put(vid)
+	return vid, nil
+}
+
+func (c *cachedSource) GetVideosByIDs(ids []string) (map[string]youtube.Video, error) {
+	videos := map[string]youtube.Video{}
+	misses := []string{}
+	for _, id := range ids {
+		if vid, ok := c.get(id); ok {
+			videos[id] = vid
+		} else {
+			misses = append(misses, id)
+		}
+	}
+	if len(misses) == 0 {
+		return videos, nil
+	}
+
+	fetched, err := c.client.GetVideosByIDs(misses)
+	for id, vid := range fetched {
+		videos[id] = vid
+		c.put(vid)
+	}
+	return videos, err
+}
+
+// GetPlaylistByID returns the playlist from the underlying source. Playlists aren't stored in
+// the repository, as their items change far more often than videos do.
+func (c *cachedSource) GetPlaylistByID(id string, maxItems int) (youtube.Playlist, error) {
+	return youtube.GetPlaylist(c.client, id, maxItems)
+}
+
+// GetChannel returns the channel from the underlying source.
+func (c *cachedSource) GetChannel(ref youtube.ChannelRef) (youtube.Channel, error) {
+	return youtube.GetChannel(c.client, ref)
+}
+
+// GetUploads returns the uploads of ch from the underlying source, as the repository can't
+// know whether it holds every upload.
+func (c *cachedSource) GetUploads(ch youtube.Channel, filter youtube.UploadsFilter) ([]string, error) {
+	return youtube.GetUploads(c.client, ch, filter)
+}
+
+// GetComments returns the comments on vid from the underlying source. Comments aren't stored in
+// the repository, as new ones are posted long after the video is.
+func (c *cachedSource) GetComments(vid youtube.Video, filter youtube.CommentFilter) ([]youtube.Comment, error) {
+	return youtube.GetComments(c.client, vid, filter)
+}
+
+// get returns the video with the given id if it is stored in the repository and has not expired.
+func (c *cachedSource) get(id string) (youtube.Video, bool) {
+	entry, err := c.repo.GetVideo(id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired) {
+			c.log.Warn("Unable to read video from repository", "id", id, "error", err)
+		}
+		return nil, false
+	}
+	c.log.Debug("Repository hit", "id", id, "fetchedAt", entry.FetchedAt)
+	return entry.Video, true
+}
+
+// put stores vid in the repository, logging rather than failing if it cannot be stored.
+func (c *cachedSource) put(vid youtube.Video) {
+	err := c.repo.PutVideo(vid)
+	if err != nil {
+		c.log.Warn("Unable to store video in repository", "id", vid.GetID(), "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// Referrer is a single link to a video from the description of another video.
+type Referrer struct {
+	// Video is the video whose description contains the link
+	Video youtube.Video
+	Link  youtube.Link
+	// URL is the link parsed out of Link
+	URL youtube.Url
+}
+
+// ReferrerIndex is the inverted index of the references between videos, mapping each video to
+// the videos whose descriptions link to it. Links in comments, and links that redirect to a
+// video, such as shortened links, aren't indexed.
+type ReferrerIndex struct {
+	mu sync.RWMutex
+	// referrers contains the links to each video, keyed by the ID of the referenced video
+	referrers map[string][]Referrer
+	// indexed contains the IDs of every video whose description has been indexed
+	indexed map[string]bool
+}
+
+// NewReferrerIndex creates the index of the references in the descriptions of videos.
+func NewReferrerIndex(videos ...youtube.Video) *ReferrerIndex {
+	idx := &ReferrerIndex{
+		referrers: map[string][]Referrer{},
+		indexed:   map[string]bool{},
+	}
+	for _, vid := range videos {
+		idx.Add(vid)
+	}
+	return idx
+}
+
+// LoadReferrerIndex creates the index of the references in the descriptions of every video in
+// repo, including the expired ones, as a reference is no less true for being old.
+func LoadReferrerIndex(repo VideoRepository) (*ReferrerIndex, error) {
+	entries, err := repo.Entries()
+	if err != nil {
+		return nil, err
+	}
+	idx := NewReferrerIndex()
+	for _, entry := range entries {
+		idx.Add(entry.Video)
+	}
+	return idx, nil
+}
+
+// Add indexes the links to videos in the description of vid, unless vid was already indexed.
+func (idx *ReferrerIndex) Add(vid youtube.Video) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.indexed[vid.GetID()] {
+		return
+	}
+	idx.indexed[vid.GetID()] = true
+
+	for _, link := range vid.GetLinksFromDescription() {
+		url, err := youtube.NewURL(link.URL)
+		// Links to the video itself, such as to one of its chapters, aren't references
+		if err != nil || url.GetID() == vid.GetID() {
+			continue
+		}
+		idx.referrers[url.GetID()] = append(idx.referrers[url.GetID()], Referrer{Video: vid, Link: link, URL: url})
+	}
+}
+
+// Referrers returns every link to the video with the given id, sorted by the ID of the video
+// containing the link.
+func (idx *ReferrerIndex) Referrers(id string) []Referrer {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	referrers := append([]Referrer{}, idx.referrers[id]...)
+	sort.SliceStable(referrers, func(i, j int) bool { return referrers[i].Video.GetID() < referrers[j].Video.GetID() })
+	return referrers
+}
+
+// Len returns the number of videos whose descriptions have been indexed.
+func (idx *ReferrerIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.indexed)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+func newTestVideo(t *testing.T, data string) youtube.Video {
+	vid, err := youtube.UnmarshalVideo([]byte(data))
+	require.NoError(t, err, "UnmarshalVideo produced an unexpected error")
+	return vid
+}
+
+func TestLoadReferrerIndex(t *testing.T) {
+	repo := newTestRepository(t, time.Hour)
+	videos := []youtube.Video{
+		newTestVideo(t, testVideoJSON),
+		newTestVideo(t, `{"id":"ztninkgZ0ws","snippet":{"title":"Sequel","description":"Part 1: https://youtu.be/-IfmgyXs7z8?t=90\nPart 2: https://youtu.be/iDIcydiQOhc\nSkip ahead: https://youtu.be/ztninkgZ0ws?t=30"}}`),
+		newTestVideo(t, `{"id":"-IfmgyXs7z8","snippet":{"title":"Original","description":"No links here"}}`),
+	}
+	for _, vid := range videos {
+		require.NoError(t, repo.PutVideo(vid), "PutVideo produced an unexpected error")
+	}
+
+	// Expired entries are indexed too
+	repo.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	idx, err := LoadReferrerIndex(repo)
+	require.NoError(t, err, "LoadReferrerIndex produced an unexpected error")
+	require.Equal(t, 3, idx.Len())
+
+	referrers := idx.Referrers("-IfmgyXs7z8")
+	require.Len(t, referrers, 2)
+	require.Equal(t, "iDIcydiQOhc", referrers[0].Video.GetID(), "expected the referrers to be sorted by ID")
+	require.Equal(t, "Watch our original Quantum Tunneling episode here", referrers[0].Link.Context())
+	require.Equal(t, "ztninkgZ0ws", referrers[1].Video.GetID())
+	require.Equal(t, 90*time.Second, referrers[1].URL.GetTimestamp())
+
+	require.Len(t, idx.Referrers("iDIcydiQOhc"), 1)
+	require.Empty(t, idx.Referrers("ztninkgZ0ws"), "expected links from a video to itself to be left out")
+}
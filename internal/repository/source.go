@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// source is a read-only youtube.Source serving videos stored in a VideoRepository.
+type source struct {
+	repo         VideoRepository
+	serveExpired bool
+}
+
+// NewSource creates a youtube.Source that only serves the videos stored in repo, never
+// fetching anything. If serveExpired is true, videos whose TTL has elapsed are still served,
+// allowing a previously crawled corpus to be graphed entirely offline.
+func NewSource(repo VideoRepository, serveExpired bool) youtube.Source {
+	return &source{
+		repo:         repo,
+		serveExpired: serveExpired,
+	}
+}
+
+func (s *source) GetVideoByTitle(title string) (youtube.Video, error) {
+	return nil, fmt.Errorf("%w: unable to search for title %s in the repository", youtube.ErrUnsupported, title)
+}
+
+func (s *source) GetVideoByURL(rawURL string) (youtube.Video, error) {
+	url, err := youtube.NewURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new URL from raw url %s: %w", rawURL, err)
+	}
+	return s.GetVideoByID(url.GetID())
+}
+
+func (s *source) GetVideoByID(id string) (youtube.Video, error) {
+	entry, err := s.repo.GetVideo(id)
+	if err == nil || (s.serveExpired && errors.Is(err, ErrExpired)) {
+		return entry.Video, nil
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrExpired) {
+		return nil, fmt.Errorf("%w: %s: %s", youtube.ErrVideoNotFound, id, err)
+	}
+	return nil, err
+}
+
+func (s *source) GetVideosByIDs(ids []string) (map[string]youtube.Video, error) {
+	videos := map[string]youtube.Video{}
+	missing := []string{}
+	for _, id := range ids {
+		vid, err := s.GetVideoByID(id)
+		if errors.Is(err, youtube.ErrVideoNotFound) {
+			missing = append(missing, id)
+			continue
+		} else if err != nil {
+			return videos, err
+		}
+		videos[id] = vid
+	}
+	if len(missing) > 0 {
+		return videos, &youtube.MissingVideosError{IDs: missing}
+	}
+	return videos, nil
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+const (
+	videoDir = "videos"
+	fileExt  = ".json"
+)
+
+var (
+	// ErrNotFound is returned when the repository does not contain the requested video.
+	ErrNotFound = errors.New("video not found in repository")
+	// ErrExpired is returned when the repository contains the requested video, but its TTL has elapsed.
+	ErrExpired = errors.New("video in repository has expired")
+)
+
+// VideoRepository stores snapshots of fetched videos, along with the links extracted from them.
+type VideoRepository interface {
+	GetVideo(id string) (Entry, error)
+	PutVideo(video youtube.Video) error
+	Entries() ([]Entry, error)
+	Stats() (Stats, error)
+	Purge(expiredOnly bool) (int, error)
+	Export(w io.Writer) error
+}
+
+// Entry is a single video snapshot stored in the repository.
+type Entry struct {
+	Video     youtube.Video
+	Links     []string
+	FetchedAt time.Time
+	Expired   bool
+}
+
+// Stats summarizes the contents of the repository.
+type Stats struct {
+	Dir     string     `json:"dir"`
+	TTL     string     `json:"ttl"`
+	Videos  int        `json:"videos"`
+	Expired int        `json:"expired"`
+	Bytes   int64      `json:"bytes"`
+	Oldest  *time.Time `json:"oldest,omitempty"`
+	Newest  *time.Time `json:"newest,omitempty"`
+}
+
+// storedEntry is the on-disk representation of an Entry.
+type storedEntry struct {
+	ID        string          `json:"id"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+	Expired   bool            `json:"expired,omitempty"`
+	Links     []string        `json:"links"`
+	Video     json.RawMessage `json:"video"`
+}
+
+type diskRepository struct {
+	mu  sync.RWMutex
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewDiskRepository creates a VideoRepository that stores one JSON file per video beneath dir.
+// Entries older than ttl are treated as missing; a ttl of 0 means entries never expire.
+func NewDiskRepository(dir string, ttl time.Duration) (VideoRepository, error) {
+	if strings.TrimSpace(dir) == "" {
+		return &diskRepository{}, fmt.Errorf("repository directory cannot be empty")
+	}
+	if ttl < 0 {
+		return &diskRepository{}, fmt.Errorf("repository ttl cannot be negative")
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, videoDir), 0o755)
+	if err != nil {
+		return &diskRepository{}, fmt.Errorf("unable to create repository directory %s: %s", dir, err)
+	}
+
+	return &diskRepository{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+// GetVideo returns the Entry for the video with the given id, or ErrNotFound or ErrExpired.
+func (r *diskRepository) GetVideo(id string) (Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, err := r.read(r.path(id))
+	if os.IsNotExist(err) {
+		return Entry{}, ErrNotFound
+	} else if err != nil {
+		return Entry{}, err
+	}
+
+	entry, err := r.toEntry(stored)
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.Expired {
+		return entry, ErrExpired
+	}
+	return entry, nil
+}
+
+// PutVideo stores a snapshot of video and the links in its description, replacing any existing entry.
+func (r *diskRepository) PutVideo(video youtube.Video) error {
+	b, err := youtube.MarshalVideo(video)
+	if err != nil {
+		return fmt.Errorf("unable to marshal video %s: %s", video.GetID(), err)
+	}
+
+	stored := storedEntry{
+		ID:        video.GetID(),
+		FetchedAt: r.now().UTC(),
+		Links:     video.GetUrlsFromDescription(),
+		Video:     b,
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("unable to marshal repository entry %s: %s", video.GetID(), err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Write to a temporary file first, so a partially written entry is never read
+	tmp, err := ioutil.TempFile(filepath.Join(r.dir, videoDir), "."+stored.ID+"-*")
+	if err != nil {
+		return fmt.Errorf("unable to create repository entry %s: %s", stored.ID, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("unable to write repository entry %s: %s", stored.ID, err)
+	}
+	return os.Rename(tmp.Name(), r.path(stored.ID))
+}
+
+// Entries returns every entry in the repository, including expired entries, sorted by video ID.
+func (r *diskRepository) Entries() ([]Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []Entry{}
+	err := r.walk(func(path string, stored storedEntry, _ os.FileInfo) error {
+		entry, err := r.toEntry(stored)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Stats returns a summary of the repository.
+func (r *diskRepository) Stats() (Stats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := Stats{Dir: r.dir, TTL: r.ttl.String()}
+	err := r.walk(func(path string, stored storedEntry, info os.FileInfo) error {
+		stats.Videos++
+		stats.Bytes += info.Size()
+		if r.isExpired(stored) {
+			stats.Expired++
+		}
+		fetchedAt := stored.FetchedAt
+		if stats.Oldest == nil || fetchedAt.Before(*stats.Oldest) {
+			stats.Oldest = &fetchedAt
+		}
+		if stats.Newest == nil || fetchedAt.After(*stats.Newest) {
+			stats.Newest = &fetchedAt
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Purge removes entries from the repository, returning the number removed. If expiredOnly
+// is true, only entries whose TTL has elapsed are removed.
+func (r *diskRepository) Purge(expiredOnly bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	err := r.walk(func(path string, stored storedEntry, _ os.FileInfo) error {
+		if expiredOnly && !r.isExpired(stored) {
+			return nil
+		}
+		err := os.Remove(path)
+		if err != nil {
+			return fmt.Errorf("unable to remove repository entry %s: %s", stored.ID, err)
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Export writes every entry in the repository to w as a JSON array.
+func (r *diskRepository) Export(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := []storedEntry{}
+	err := r.walk(func(path string, s storedEntry, _ os.FileInfo) error {
+		s.Expired = r.isExpired(s)
+		stored = append(stored, s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stored)
+}
+
+// walk calls fn for every entry in the repository, sorted by video ID. The caller must hold r.mu.
+func (r *diskRepository) walk(fn func(path string, stored storedEntry, info os.FileInfo) error) error {
+	infos, err := ioutil.ReadDir(filepath.Join(r.dir, videoDir))
+	if err != nil {
+		return fmt.Errorf("unable to read repository directory %s: %s", r.dir, err)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), fileExt) {
+			continue
+		}
+		path := filepath.Join(r.dir, videoDir, info.Name())
+		stored, err := r.read(path)
+		if err != nil {
+			return err
+		}
+		err = fn(path, stored, info)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *diskRepository) read(path string) (storedEntry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return storedEntry{}, err
+	}
+	stored := storedEntry{}
+	err = json.Unmarshal(b, &stored)
+	if err != nil {
+		return storedEntry{}, fmt.Errorf("unable to unmarshal repository entry %s: %s", path, err)
+	}
+	return stored, nil
+}
+
+func (r *diskRepository) toEntry(stored storedEntry) (Entry, error) {
+	vid, err := youtube.UnmarshalVideo(stored.Video)
+	if err != nil {
+		return Entry{}, fmt.Errorf("unable to restore repository entry %s: %s", stored.ID, err)
+	}
+	return Entry{
+		Video:     vid,
+		Links:     stored.Links,
+		FetchedAt: stored.FetchedAt,
+		Expired:   r.isExpired(stored),
+	}, nil
+}
+
+func (r *diskRepository) isExpired(stored storedEntry) bool {
+	return r.ttl > 0 && r.now().Sub(stored.FetchedAt) > r.ttl
+}
+
+func (r *diskRepository) path(id string) string {
+	return filepath.Join(r.dir, videoDir, filepath.Base(id)+fileExt)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+const testVideoJSON = `{"id":"iDIcydiQOhc","snippet":{"title":"New Results in Quantum Tunneling","channelTitle":"PBS Space Time","description":"Watch our original Quantum Tunneling episode here:\nhttps://youtu.be/-IfmgyXs7z8"}}`
+
+func newTestRepository(t *testing.T, ttl time.Duration) *diskRepository {
+	repo, err := NewDiskRepository(t.TempDir(), ttl)
+	require.NoError(t, err, "NewDiskRepository produced an unexpected error")
+	return repo.(*diskRepository)
+}
+
+func TestDiskRepository_PutGetVideo(t *testing.T) {
+	repo := newTestRepository(t, time.Hour)
+	vid, err := youtube.UnmarshalVideo([]byte(testVideoJSON))
+	require.NoError(t, err, "UnmarshalVideo produced an unexpected error")
+
+	_, err = repo.GetVideo(vid.GetID())
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, repo.PutVideo(vid), "PutVideo produced an unexpected error")
+
+	entry, err := repo.GetVideo(vid.GetID())
+	require.NoError(t, err, "GetVideo produced an unexpected error")
+	require.Equal(t, vid.GetTitle(), entry.Video.GetTitle())
+	require.Equal(t, vid.GetDescription(), entry.Video.GetDescription())
+	require.Equal(t, []string{"https://youtu.be/-IfmgyXs7z8"}, entry.Links)
+}
+
+func TestDiskRepository_Expiry(t *testing.T) {
+	repo := newTestRepository(t, time.Hour)
+	vid, err := youtube.UnmarshalVideo([]byte(testVideoJSON))
+	require.NoError(t, err, "UnmarshalVideo produced an unexpected error")
+	require.NoError(t, repo.PutVideo(vid), "PutVideo produced an unexpected error")
+
+	repo.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = repo.GetVideo(vid.GetID())
+	require.ErrorIs(t, err, ErrExpired)
+
+	stats, err := repo.Stats()
+	require.NoError(t, err, "Stats produced an unexpected error")
+	require.Equal(t, 1, stats.Videos)
+	require.Equal(t, 1, stats.Expired)
+
+	removed, err := repo.Purge(true)
+	require.NoError(t, err, "Purge produced an unexpected error")
+	require.Equal(t, 1, removed)
+
+	var buf bytes.Buffer
+	require.NoError(t, repo.Export(&buf), "Export produced an unexpected error")
+	require.JSONEq(t, "[]", buf.String())
+}
@@ -0,0 +1,182 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// Kinds of object an external link can refer to.
+const (
+	KindDOI   = "doi"
+	KindArXiv = "arxiv"
+	KindLink  = "link"
+)
+
+const (
+	// doiRegex matches a DOI anywhere in a link, such as 10.1088/1367-2630/ab5c7d
+	doiRegex = `(?i)\b(10\.\d{4,9}/[^\s?#&]+)`
+	// doiIDRegex matches a DOI written as an identifier, such as doi:10.1088/1367-2630/ab5c7d
+	doiIDRegex = `(?i)^doi:\s*(10\.\d{4,9}/\S+)$`
+	// arXivIDRegex matches an arXiv ID, either new style (2103.12345v2) or old style (hep-th/9901001),
+	// capturing the ID without its version
+	arXivIDRegex = `(?i)^(?:arxiv:\s*)?(\d{4}\.\d{4,5}|[a-z-]+(?:\.[a-z]{2})?/\d{7})(?:v\d+)?(?:\.pdf)?$`
+	// arXivDOIRegex matches the DOI of an arXiv paper, such as 10.48550/arXiv.2103.12345
+	arXivDOIRegex = `(?i)^10\.48550/arxiv\.(.+)$`
+
+	doiURLFormat   = "https://doi.org/%s"
+	arXivURLFormat = "https://arxiv.org/abs/%s"
+	doiDomain      = "doi.org"
+	arXivDomain    = "arxiv.org"
+)
+
+var (
+	doiRe      = regexp.MustCompile(doiRegex)
+	doiIDRe    = regexp.MustCompile(doiIDRegex)
+	arXivIDRe  = regexp.MustCompile(arXivIDRegex)
+	arXivDOIRe = regexp.MustCompile(arXivDOIRegex)
+)
+
+// ErrInvalidLink is returned by Parse when its input is neither a link nor a DOI or arXiv ID.
+var ErrInvalidLink = errors.New("invalid external link")
+
+// doiSuffixes are the pages of an article that publishers append to its DOI in their links, such
+// as https://iopscience.iop.org/article/10.1088/1367-2630/ab5c7d/pdf.
+var doiSuffixes = []string{"/pdf", "/epdf", "/full", "/fulltext", "/abstract", "/meta", "/html", "/references", "/figures", ".pdf"}
+
+// doiPrefixes are the DOI prefixes of publishers whose links hold the rest of the DOI without the
+// prefix, keyed by the host and path preceding it, such as https://www.nature.com/articles/s41586-021-03213-y.
+var doiPrefixes = map[string]string{
+	"nature.com/articles/": "10.1038",
+}
+
+// trackingParams are the query parameters that only track where a link was clicked, and are
+// left out of the canonical link.
+var trackingParams = []string{"fbclid", "gclid", "igshid", "si", "ref", "ref_src", "feature"}
+
+// Reference is the object an external link refers to, such as a paper or a shop, identified
+// canonically so that every link to the same paper is the same reference.
+type Reference struct {
+	Kind string
+	// ID is the canonical identifier of the object, such as doi:10.1088/1367-2630/ab5c7d,
+	// arxiv:2103.12345 or, for any other link, the canonical link itself
+	ID string
+	// URL is the canonical link to the object
+	URL string
+	// Domain is the domain the object belongs to, without www.
+	Domain string
+}
+
+// Parse returns the reference that link, which may also be a DOI or arXiv ID such as
+// doi:10.1088/1367-2630/ab5c7d or arXiv:2103.12345, refers to. Links to a paper, whether on
+// doi.org, arxiv.org or a publisher's site, are identified by the paper's DOI or arXiv ID. Any
+// other link is identified by the link itself, without its fragment or tracking parameters.
+func Parse(link string) (Reference, error) {
+	trimmed := strings.TrimSpace(link)
+	if res := doiIDRe.FindStringSubmatch(trimmed); len(res) == 2 {
+		return doiReference(res[1]), nil
+	}
+	if strings.HasPrefix(strings.ToLower(trimmed), "arxiv:") {
+		res := arXivIDRe.FindStringSubmatch(trimmed)
+		if len(res) != 2 {
+			return Reference{}, fmt.Errorf("%w: Unable to parse arXiv ID %s", ErrInvalidLink, link)
+		}
+		return arXivReference(res[1]), nil
+	}
+
+	u, err := neturl.Parse(trimmed)
+	if err != nil {
+		return Reference{}, fmt.Errorf("%w: Unable to parse link %s: %s", ErrInvalidLink, link, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Reference{}, fmt.Errorf("%w: %s is not a web link", ErrInvalidLink, link)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	if host == arXivDomain || strings.HasSuffix(host, "."+arXivDomain) {
+		segments := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+		if len(segments) == 2 {
+			if res := arXivIDRe.FindStringSubmatch(segments[1]); len(res) == 2 {
+				return arXivReference(res[1]), nil
+			}
+		}
+	}
+	if res := doiRe.FindStringSubmatch(u.Path + "?" + u.RawQuery); len(res) == 2 {
+		if doi, err := neturl.PathUnescape(res[1]); err == nil {
+			return doiReference(trimDOISuffixes(doi)), nil
+		}
+	}
+	for prefix, doiPrefix := range doiPrefixes {
+		if rest := strings.TrimPrefix(host+u.Path, prefix); rest != host+u.Path && rest != "" && !strings.Contains(rest, "/") {
+			return doiReference(doiPrefix + "/" + trimDOISuffixes(rest)), nil
+		}
+	}
+	return linkReference(u, host), nil
+}
+
+// doiReference returns the reference to the paper with the given DOI, which is an arXiv
+// reference if the DOI was minted by arXiv.
+func doiReference(doi string) Reference {
+	doi = strings.ToLower(strings.TrimRight(doi, "/"))
+	if res := arXivDOIRe.FindStringSubmatch(doi); len(res) == 2 {
+		if id := arXivIDRe.FindStringSubmatch(res[1]); len(id) == 2 {
+			return arXivReference(id[1])
+		}
+	}
+	return Reference{Kind: KindDOI, ID: KindDOI + ":" + doi, URL: fmt.Sprintf(doiURLFormat, doi), Domain: doiDomain}
+}
+
+// arXivReference returns the reference to the paper with the given arXiv ID.
+func arXivReference(id string) Reference {
+	id = strings.ToLower(id)
+	return Reference{Kind: KindArXiv, ID: KindArXiv + ":" + id, URL: fmt.Sprintf(arXivURLFormat, id), Domain: arXivDomain}
+}
+
+// trimDOISuffixes returns doi without any of the pages that publishers append to it in their links.
+func trimDOISuffixes(doi string) string {
+	for {
+		trimmed := strings.TrimRight(doi, "/")
+		for _, suffix := range doiSuffixes {
+			if len(trimmed) > len(suffix) && strings.EqualFold(trimmed[len(trimmed)-len(suffix):], suffix) {
+				trimmed = trimmed[:len(trimmed)-len(suffix)]
+			}
+		}
+		if trimmed == doi {
+			return doi
+		}
+		doi = trimmed
+	}
+}
+
+// linkReference returns the reference to any other link, identified by the link without its
+// fragment, tracking parameters, www. or trailing slash.
+func linkReference(u *neturl.URL, host string) Reference {
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || contains(trackingParams, strings.ToLower(key)) {
+			query.Del(key)
+		}
+	}
+
+	canonical := neturl.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     strings.TrimRight(u.Path, "/"),
+		RawQuery: query.Encode(),
+	}
+	if port := u.Port(); port != "" {
+		canonical.Host = host + ":" + port
+	}
+	return Reference{Kind: KindLink, ID: canonical.String(), URL: canonical.String(), Domain: host}
+}
+
+func contains(someList []string, someElement string) bool {
+	for _, element := range someList {
+		if element == someElement {
+			return true
+		}
+	}
+	return false
+}
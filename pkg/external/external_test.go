@@ -0,0 +1,64 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	paper := Reference{Kind: KindDOI, ID: "doi:10.1088/1367-2630/ab5c7d", URL: "https://doi.org/10.1088/1367-2630/ab5c7d", Domain: "doi.org"}
+	preprint := Reference{Kind: KindArXiv, ID: "arxiv:2103.12345", URL: "https://arxiv.org/abs/2103.12345", Domain: "arxiv.org"}
+
+	tests := []struct {
+		input    string
+		expected Reference
+	}{
+		// DOIs
+		{"doi:10.1088/1367-2630/ab5c7d", paper},
+		{"DOI: 10.1088/1367-2630/AB5C7D", paper},
+		{"https://doi.org/10.1088/1367-2630/ab5c7d", paper},
+		{"http://dx.doi.org/10.1088/1367-2630/ab5c7d", paper},
+		{"https://doi.org/10.1088%2F1367-2630%2Fab5c7d", paper},
+		{"https://iopscience.iop.org/article/10.1088/1367-2630/ab5c7d", paper},
+		{"https://iopscience.iop.org/article/10.1088/1367-2630/ab5c7d/pdf", paper},
+		{"https://iopscience.iop.org/article/10.1088/1367-2630/ab5c7d/meta#references", paper},
+		{"https://onlinelibrary.wiley.com/doi/full/10.1088/1367-2630/ab5c7d", paper},
+		{"https://example.com/cite?doi=10.1088/1367-2630/ab5c7d&format=bib", paper},
+		{"https://www.nature.com/articles/s41586-021-03213-y", Reference{Kind: KindDOI, ID: "doi:10.1038/s41586-021-03213-y", URL: "https://doi.org/10.1038/s41586-021-03213-y", Domain: "doi.org"}},
+		{"https://www.nature.com/articles/s41586-021-03213-y.pdf", Reference{Kind: KindDOI, ID: "doi:10.1038/s41586-021-03213-y", URL: "https://doi.org/10.1038/s41586-021-03213-y", Domain: "doi.org"}},
+
+		// arXiv IDs
+		{"arXiv:2103.12345", preprint},
+		{"arxiv: 2103.12345v3", preprint},
+		{"https://arxiv.org/abs/2103.12345", preprint},
+		{"https://arxiv.org/abs/2103.12345v2", preprint},
+		{"https://arxiv.org/pdf/2103.12345.pdf", preprint},
+		{"https://arxiv.org/pdf/2103.12345v1.pdf", preprint},
+		{"http://export.arxiv.org/abs/2103.12345", preprint},
+		{"https://doi.org/10.48550/arXiv.2103.12345", preprint},
+		{"https://arxiv.org/abs/hep-th/9901001", Reference{Kind: KindArXiv, ID: "arxiv:hep-th/9901001", URL: "https://arxiv.org/abs/hep-th/9901001", Domain: "arxiv.org"}},
+
+		// Other links
+		{"https://www.patreon.com/pbsspacetime/", Reference{Kind: KindLink, ID: "https://patreon.com/pbsspacetime", URL: "https://patreon.com/pbsspacetime", Domain: "patreon.com"}},
+		{"http://Shop.Example.com/merch?utm_source=youtube&color=blue&fbclid=abc#sizes", Reference{Kind: KindLink, ID: "https://shop.example.com/merch?color=blue", URL: "https://shop.example.com/merch?color=blue", Domain: "shop.example.com"}},
+		{"https://example.com", Reference{Kind: KindLink, ID: "https://example.com", URL: "https://example.com", Domain: "example.com"}},
+		{"https://arxiv.org/list/astro-ph/new", Reference{Kind: KindLink, ID: "https://arxiv.org/list/astro-ph/new", URL: "https://arxiv.org/list/astro-ph/new", Domain: "arxiv.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := Parse(tt.input)
+			require.NoError(t, err, "Parse produced an unexpected error")
+			require.Equal(t, tt.expected, ref)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "not a link", "arXiv:soon", "ftp://example.com/paper.pdf", "mailto:hello@example.com", "https://"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			require.ErrorIs(t, err, ErrInvalidLink)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aggregations supported by Aggregate.
+const (
+	AggregateNone    = ""
+	AggregateChannel = "channel"
+)
+
+// Aggregations lists every aggregation supported by Aggregate, other than AggregateNone.
+var Aggregations = []string{AggregateChannel}
+
+// channelURLFormat is the URL of a channel's page, given its ID.
+const channelURLFormat = "https://www.youtube.com/channel/%s"
+
+// relationReferences is the relation of the edges of an aggregated graph.
+const relationReferences = "references"
+
+// Aggregate returns the projection of g described by by, or g itself if by is AggregateNone.
+func Aggregate(g Graph, by string) (Graph, error) {
+	switch strings.ToLower(strings.TrimSpace(by)) {
+	case AggregateNone:
+		return g, nil
+	case AggregateChannel:
+		return AggregateByChannel(g), nil
+	}
+	return nil, fmt.Errorf("unsupported aggregation %s; Must be one of %s", by, strings.Join(Aggregations, ", "))
+}
+
+// AggregateByChannel returns the projection of g into a graph of channels. Each video node of g
+// becomes the node of its channel, and each edge between two video nodes becomes an edge between
+// their channels, weighted by the number of times the videos of the source channel reference
+// the videos of the target channel. References within a channel are kept as a weighted loop.
+// Nodes that aren't videos, such as placeholders and playlists, are left out, along with their edges.
+func AggregateByChannel(g Graph) Graph {
+	agg := NewGraph("", "Youtube Channel Dependencies", "ydg-channel")
+
+	channelOf := map[string]string{}
+	titles := map[string]string{}
+	counts := map[string]int{}
+	for _, n := range g.GetNodes() {
+		md, ok := n.GetVideoMetadata()
+		if !ok || md.ChannelID == "" {
+			continue
+		}
+		channelOf[n.GetID()] = md.ChannelID
+		counts[md.ChannelID]++
+		if titles[md.ChannelID] == "" {
+			titles[md.ChannelID] = md.ChannelTitle
+		}
+	}
+
+	channels := map[string]Node{}
+	for id, count := range counts {
+		label := titles[id]
+		if strings.TrimSpace(label) == "" {
+			label = id
+		}
+		n, err := NewChannelNode(id, label, ChannelMetadata{VideoCount: count, URL: fmt.Sprintf(channelURLFormat, id)})
+		if err != nil {
+			continue
+		}
+		channels[id] = n
+		agg.AddNode(n)
+	}
+
+	// Weights are counted in the order the edges are first seen, so that the output is stable
+	type pair struct{ source, target string }
+	weights := map[pair]int{}
+	order := []pair{}
+	for _, e := range g.GetEdges() {
+		source, ok := channelOf[e.GetSource()]
+		if !ok {
+			continue
+		}
+		target, ok := channelOf[e.GetTarget()]
+		if !ok {
+			continue
+		}
+		p := pair{source, target}
+		if weights[p] == 0 {
+			order = append(order, p)
+		}
+		weights[p]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return weights[order[i]] > weights[order[j]] })
+
+	for _, p := range order {
+		agg.AddEdgeWithMetadata(channels[p.source], channels[p.target], relationReferences, EdgeMetadata{Weight: weights[p]})
+	}
+	return agg
+}
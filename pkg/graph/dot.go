@@ -0,0 +1,168 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ToDOT returns a Graphviz representation of the graph, as a digraph in which the video and
+// playlist nodes are grouped into one cluster per channel, the external nodes into one cluster
+// per domain, and each edge is labeled with its relation.
+/*
+digraph "Youtube Video Dependencies" {
+    label="Youtube Video Dependencies";
+    subgraph "cluster_UC7_gcs09iThXybpVgjHZ_7g" {
+        label="PBS Space Time";
+        "iDIcydiQOhc" [label="New Results in Quantum Tunneling vs. The Speed of Light"];
+    }
+    "iDIcydiQOhc" -> "-IfmgyXs7z8" [label="references_via_description"];
+}
+*/
+func (g *graph) ToDOT() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// Group the nodes by channel, sorting everything so the output is stable between runs
+	clusters := map[string][]Node{}
+	titles := map[string]string{}
+	unclustered := []Node{}
+	for _, n := range g.sortedNodes() {
+		channelID, channelTitle := nodeChannel(n)
+		if channelID == "" {
+			unclustered = append(unclustered, n)
+			continue
+		}
+		clusters[channelID] = append(clusters[channelID], n)
+		if titles[channelID] == "" {
+			titles[channelID] = channelTitle
+		}
+	}
+	channelIDs := []string{}
+	for id := range clusters {
+		channelIDs = append(channelIDs, id)
+	}
+	sort.Strings(channelIDs)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Label))
+	fmt.Fprintf(&b, "    label=%s;\n", dotQuote(g.Label))
+	for _, id := range channelIDs {
+		title := titles[id]
+		if title == "" {
+			title = id
+		}
+		fmt.Fprintf(&b, "    subgraph %s {\n", dotQuote("cluster_"+id))
+		fmt.Fprintf(&b, "        label=%s;\n", dotQuote(title))
+		for _, n := range clusters[id] {
+			fmt.Fprintf(&b, "        %s;\n", dotNode(n))
+		}
+		b.WriteString("    }\n")
+	}
+	for _, n := range unclustered {
+		fmt.Fprintf(&b, "    %s;\n", dotNode(n))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "    %s;\n", dotEdge(e))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// sortedNodes returns the nodes of the graph sorted by ID. The caller must hold g.mu.
+func (g *graph) sortedNodes() []Node {
+	nodes := []Node{}
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].GetID() < nodes[j].GetID() })
+	return nodes
+}
+
+// nodeChannel returns the ID and title of the channel owning the video or playlist n represents,
+// or the domain of the object an external node represents.
+func nodeChannel(n Node) (string, string) {
+	if md, ok := n.GetExternalMetadata(); ok {
+		return md.Domain, md.Domain
+	}
+	if md, ok := n.GetVideoMetadata(); ok {
+		return md.ChannelID, md.ChannelTitle
+	}
+	if md, ok := n.GetPlaylistMetadata(); ok {
+		return md.ChannelID, md.ChannelTitle
+	}
+	return "", ""
+}
+
+// dotNode returns the DOT statement declaring n. Placeholder nodes are dashed and include their
+// status, playlist nodes are drawn as folders and external nodes as notes.
+func dotNode(n Node) string {
+	if status := n.GetStatus(); status != "" {
+		return fmt.Sprintf("%s [label=%s, style=dashed]", dotQuote(n.GetID()), dotQuote(fmt.Sprintf("[%s] %s", status, n.GetLabel())))
+	}
+	if _, ok := n.GetPlaylistMetadata(); ok {
+		return fmt.Sprintf("%s [label=%s, shape=folder]", dotQuote(n.GetID()), dotQuote(n.GetLabel()))
+	}
+	if _, ok := n.GetExternalMetadata(); ok {
+		return fmt.Sprintf("%s [label=%s, shape=note]", dotQuote(n.GetID()), dotQuote(n.GetLabel()))
+	}
+	if md, ok := n.GetChannelMetadata(); ok {
+		return fmt.Sprintf("%s [label=%s, shape=box]", dotQuote(n.GetID()), dotQuote(fmt.Sprintf("%s (%d videos)", n.GetLabel(), md.VideoCount)))
+	}
+	return fmt.Sprintf("%s [label=%s]", dotQuote(n.GetID()), dotQuote(n.GetLabel()))
+}
+
+// dotEdge returns the DOT statement declaring e. Weighted edges include their weight in their
+// label, and are drawn thicker the heavier they are. The timestamps the references link to are
+// included in its label, and the chapters they appear under, their context and the link they
+// were written as, if it redirected to the target, in its tooltip.
+func dotEdge(e Edge) string {
+	md := e.GetMetadata()
+	label := e.GetRelation()
+	if len(md.Timestamps) > 0 {
+		timestamps := []string{}
+		for _, ts := range md.Timestamps {
+			timestamps = append(timestamps, (time.Duration(ts) * time.Second).String())
+		}
+		label = fmt.Sprintf("%s @ %s", label, strings.Join(timestamps, ", "))
+	}
+	if md.Weight > 0 {
+		return fmt.Sprintf("%s -> %s [label=%s, weight=%d, penwidth=%.1f]", dotQuote(e.GetSource()), dotQuote(e.GetTarget()), dotQuote(fmt.Sprintf("%s (%d)", label, md.Weight)), md.Weight, penwidth(md.Weight))
+	}
+	tooltip := []string{}
+	titles := map[string]bool{}
+	for _, ch := range md.Chapters {
+		tooltip = append(tooltip, ch.Title)
+		titles[ch.Title] = true
+	}
+	if md.Context != "" && !titles[md.Context] {
+		tooltip = append(tooltip, md.Context)
+	}
+	if md.OriginalURL != "" {
+		tooltip = append(tooltip, "via "+md.OriginalURL)
+	}
+	if len(tooltip) > 0 {
+		return fmt.Sprintf("%s -> %s [label=%s, tooltip=%s]", dotQuote(e.GetSource()), dotQuote(e.GetTarget()), dotQuote(label), dotQuote(strings.Join(tooltip, "\n")))
+	}
+	return fmt.Sprintf("%s -> %s [label=%s]", dotQuote(e.GetSource()), dotQuote(e.GetTarget()), dotQuote(label))
+}
+
+// penwidth returns the width of an edge with the given weight, growing logarithmically so that
+// the heaviest edges don't drown out the rest.
+func penwidth(weight int) float64 {
+	return 1 + math.Log2(float64(weight))
+}
+
+// dotQuote returns s as a DOT quoted string, escaping any characters DOT treats specially.
+func dotQuote(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return `"` + r.Replace(s) + `"`
+}
@@ -11,6 +11,7 @@ type Edge interface {
 	GetSource() string
 	GetTarget() string
 	GetRelation() string
+	GetMetadata() EdgeMetadata
 	ToJSON() string
 }
 
@@ -24,12 +25,83 @@ type Edge interface {
 }
 */
 type edge struct {
-	ID       string `json:"id"`
-	Source   string `json:"source"`
-	Target   string `json:"target"`
-	Relation string `json:"relation"`
-	Directed bool   `json:"directed"`
-	Label    string `json:"label"`
+	ID       string        `json:"id"`
+	Source   string        `json:"source"`
+	Target   string        `json:"target"`
+	Relation string        `json:"relation"`
+	Directed bool          `json:"directed"`
+	Label    string        `json:"label"`
+	Metadata *EdgeMetadata `json:"metadata,omitempty"`
+}
+
+// EdgeMetadata describes an edge beyond its relation.
+/*
+{
+    "weight": 3,
+    "context": "Watch our original Quantum Tunneling episode here",
+    "timestamps": [90, 420],
+    "chapters": [
+        {
+            "title": "Black holes",
+            "start": 195
+        }
+    ],
+    "originalUrl": "https://bit.ly/3abcDEF"
+}
+*/
+type EdgeMetadata struct {
+	// Weight is the number of references an edge of an aggregated graph stands for
+	Weight int `json:"weight,omitempty"`
+	// Context is the text describing the reference, such as the line of the description it appears on
+	Context string `json:"context,omitempty"`
+	// Timestamps are the numbers of seconds into the target that the references link to
+	Timestamps []int `json:"timestamps,omitempty"`
+	// Chapters are the chapters of the source's description that the references appear under
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// OriginalURL is the link the reference was written as, if it redirected to the target, such as a shortened link
+	OriginalURL string `json:"originalUrl,omitempty"`
+}
+
+// merge adds the timestamps and chapters of other that md doesn't have yet, and fills in
+// whatever else md is missing from other.
+func (md *EdgeMetadata) merge(other EdgeMetadata) {
+	timestamps := map[int]bool{}
+	for _, ts := range md.Timestamps {
+		timestamps[ts] = true
+	}
+	for _, ts := range other.Timestamps {
+		if !timestamps[ts] {
+			timestamps[ts] = true
+			md.Timestamps = append(md.Timestamps, ts)
+		}
+	}
+
+	chapters := map[Chapter]bool{}
+	for _, ch := range md.Chapters {
+		chapters[ch] = true
+	}
+	for _, ch := range other.Chapters {
+		if !chapters[ch] {
+			chapters[ch] = true
+			md.Chapters = append(md.Chapters, ch)
+		}
+	}
+
+	if md.Weight == 0 {
+		md.Weight = other.Weight
+	}
+	if md.Context == "" {
+		md.Context = other.Context
+	}
+	if md.OriginalURL == "" {
+		md.OriginalURL = other.OriginalURL
+	}
+}
+
+// Chapter is a section of a video, starting the given number of seconds into it.
+type Chapter struct {
+	Title string `json:"title"`
+	Start int    `json:"start"`
 }
 
 func NewEdge(source, target, relation string) Edge {
@@ -43,6 +115,37 @@ func NewEdge(source, target, relation string) Edge {
 	}
 }
 
+// NewEdgeWithMetadata creates an edge described by md, labeled with its context if it has one.
+func NewEdgeWithMetadata(source, target, relation string, md EdgeMetadata) Edge {
+	e := NewEdge(source, target, relation).(*edge)
+	if md.Context != "" {
+		e.Label = md.Context
+	}
+	e.Metadata = &md
+	return e
+}
+
+// setRelation relabels the edge with relation, unless it is labeled with its context.
+func (e *edge) setRelation(relation string) {
+	if e.Label == e.Relation {
+		e.Label = relation
+	}
+	e.Relation = relation
+}
+
+// mergeMetadata merges md into the metadata of the edge, which describes another reference
+// between the same nodes.
+func (e *edge) mergeMetadata(md EdgeMetadata) {
+	if e.Metadata == nil {
+		if md.Context != "" && e.Label == e.Relation {
+			e.Label = md.Context
+		}
+		e.Metadata = &md
+		return
+	}
+	e.Metadata.merge(md)
+}
+
 func (e *edge) GetID() string {
 	return e.ID
 }
@@ -59,6 +162,14 @@ func (e *edge) GetRelation() string {
 	return e.Relation
 }
 
+// GetMetadata returns the metadata of the edge, which is empty unless the edge was created with metadata.
+func (e *edge) GetMetadata() EdgeMetadata {
+	if e.Metadata == nil {
+		return EdgeMetadata{}
+	}
+	return *e.Metadata
+}
+
 func (e *edge) ToJSON() string {
 	b, _ := json.Marshal(e)
 	return string(b)
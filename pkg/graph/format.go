@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Output formats supported by Encode.
+const (
+	FormatJGF        = "jgf"
+	FormatCustomJSON = "custom-json"
+	FormatDOT        = "dot"
+)
+
+// Formats lists every output format supported by Encode.
+var Formats = []string{FormatJGF, FormatCustomJSON, FormatDOT}
+
+// Encode returns the string representation of g in the given format.
+func Encode(g Graph, format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJGF:
+		return g.ToJSON(), nil
+	case FormatCustomJSON:
+		return g.ToCustomJSON(), nil
+	case FormatDOT:
+		return g.ToDOT(), nil
+	}
+	return "", fmt.Errorf("unsupported format %s; Must be one of %s", format, strings.Join(Formats, ", "))
+}
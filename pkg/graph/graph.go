@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 )
@@ -12,9 +13,14 @@ type Graph interface {
 	GetID() string
 	AddNode(n Node)
 	AddEdge(parent Node, child Node, relation string)
+	AddEdgeWithMetadata(parent Node, child Node, relation string, md EdgeMetadata)
+	GetEdge(parent Node, child Node) (Edge, bool)
 	GetNodeByID(id string) (Node, error)
+	GetNodes() []Node
+	GetEdges() []Edge
 	ToJSON() string
 	ToCustomJSON() string
+	ToDOT() string
 }
 
 // Adhere to jsongraphformatv2
@@ -54,6 +60,8 @@ type Graph interface {
    },
 */
 type graph struct {
+	mu sync.RWMutex
+
 	ID    string          `json:"id"`
 	Label string          `json:"label"`
 	Type  string          `json:"type"`
@@ -96,42 +104,86 @@ func (g *graph) GetID() string {
 }
 
 // AddNode adds Node n to the graph if it is not already in the graph.
+// It is safe to call from multiple goroutines.
 func (g *graph) AddNode(n Node) {
-	// Don't double-add nodes
-	if _, exists := g.Nodes[n.GetID()]; !exists {
-		g.Nodes[n.GetID()] = n
-	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.addNode(n)
 }
 
 // AddEdge adds a directed edge between Node parent and Node child, labeling it with the given relation.
-// If (parent|child) do not exist in the graph yet, they will be added.
+// If (parent|child) do not exist in the graph yet, they will be added. If the edge already exists,
+// it is relabeled with the given relation instead.
+// It is safe to call from multiple goroutines.
 func (g *graph) AddEdge(parent Node, child Node, relation string) {
-	// No-op if the graph already contains this edge
-	if g.containsEdge(parent, child) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.addEdge(parent, child, relation, nil)
+}
+
+// AddEdgeWithMetadata adds a directed edge between Node parent and Node child the same way
+// AddEdge does, describing it with md.
+// It is safe to call from multiple goroutines.
+func (g *graph) AddEdgeWithMetadata(parent Node, child Node, relation string, md EdgeMetadata) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.addEdge(parent, child, relation, &md)
+}
+
+// addEdge adds a directed edge between Node parent and Node child, described by md if it isn't
+// nil. If the graph already contains the edge, it takes the given relation, unless it is empty,
+// and md is merged into its metadata instead. The caller must hold g.mu.
+func (g *graph) addEdge(parent Node, child Node, relation string, md *EdgeMetadata) {
+	if e := g.findEdge(parent, child); e != nil {
+		if strings.TrimSpace(relation) != "" {
+			e.setRelation(relation)
+		}
+		if md != nil {
+			e.mergeMetadata(*md)
+		}
 		return
 	}
 
 	// If the graph doesn't have the edge, ensure that the parent/child nodes
 	// exist in the graph (adding them if either doesn't exist yet)
-	// TODO: Use g.AddNode(...) here?
-	if _, ok := g.Nodes[parent.GetID()]; !ok {
-		g.Nodes[parent.GetID()] = parent
-	}
-	if _, ok := g.Nodes[child.GetID()]; !ok {
-		g.Nodes[child.GetID()] = child
-	}
+	g.addNode(parent)
+	g.addNode(child)
 
 	// Enforce non-empty string for relation
 	if strings.TrimSpace(relation) == "" {
 		relation = "references"
 	}
 
-	e := NewEdge(parent.GetID(), child.GetID(), relation)
+	var e Edge
+	if md != nil {
+		e = NewEdgeWithMetadata(parent.GetID(), child.GetID(), relation, *md)
+	} else {
+		e = NewEdge(parent.GetID(), child.GetID(), relation)
+	}
 	g.Edges = append(g.Edges, e)
 }
 
+// GetEdge returns the directed edge between Node parent and Node child, and false if the graph
+// doesn't contain it.
+func (g *graph) GetEdge(parent Node, child Node) (Edge, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	e := g.findEdge(parent, child)
+	if e == nil {
+		return nil, false
+	}
+	return e, true
+}
+
 // GetNodeByID returns the Node whose ID is equivalent to the given id, or nil.
 func (g *graph) GetNodeByID(id string) (Node, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
 	n, ok := g.Nodes[id]
 	if !ok {
 		return &node{}, fmt.Errorf("unable to find node with id %s", id)
@@ -139,8 +191,29 @@ func (g *graph) GetNodeByID(id string) (Node, error) {
 	return n, nil
 }
 
+// GetNodes returns every node in the graph, sorted by ID.
+func (g *graph) GetNodes() []Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.sortedNodes()
+}
+
+// GetEdges returns every edge in the graph, in the order they were added.
+func (g *graph) GetEdges() []Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	edges := make([]Edge, len(g.Edges))
+	copy(edges, g.Edges)
+	return edges
+}
+
 // ToJSON returns a string representation of the graph, following the json graph schema v2.
 func (g *graph) ToJSON() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
 	b, _ := json.Marshal(g)
 	return string(b)
 }
@@ -166,6 +239,9 @@ the format is
 }
 */
 func (g *graph) ToCustomJSON() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
 	gc := &graphCustomJSON{
 		ID:    g.ID,
 		Label: g.Label,
@@ -187,12 +263,23 @@ func (gc *graphCustomJSON) ToJSON() string {
 	return string(b)
 }
 
-// containsEdge returns a bool, indicating if a directed edge between Node parent and Node child exists.
-func (g *graph) containsEdge(parent Node, child Node) bool {
+// addNode adds Node n to the graph if it is not already in the graph. The caller must hold g.mu.
+func (g *graph) addNode(n Node) {
+	// Don't double-add nodes
+	if _, exists := g.Nodes[n.GetID()]; !exists {
+		g.Nodes[n.GetID()] = n
+	}
+}
+
+// findEdge returns the directed edge between Node parent and Node child, or nil if the graph
+// doesn't contain it.
+func (g *graph) findEdge(parent Node, child Node) *edge {
 	for _, e := range g.Edges {
 		if e.GetSource() == parent.GetID() && e.GetTarget() == child.GetID() {
-			return true
+			if found, ok := e.(*edge); ok {
+				return found
+			}
 		}
 	}
-	return false
+	return nil
 }
@@ -1 +1,241 @@
 package graph
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_AddEdgeConcurrent(t *testing.T) {
+	g := NewGraph("", "", "")
+	root, err := NewNode("root", "Root")
+	require.NoError(t, err, "NewNode produced an unexpected error")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child, err := NewNode(fmt.Sprintf("child-%d", i%10), "Child")
+			require.NoError(t, err, "NewNode produced an unexpected error")
+			g.AddEdge(root, child, "")
+		}(i)
+	}
+	wg.Wait()
+
+	gg := g.(*graph)
+	require.Len(t, gg.Nodes, 11, "expected the root and each unique child to be added once")
+	require.Len(t, gg.Edges, 10, "expected each unique edge to be added once")
+}
+
+func TestGraph_AddEdgeWithMetadataMerges(t *testing.T) {
+	g := NewGraph("", "", "")
+	parent, err := NewNode("parent", "Parent")
+	require.NoError(t, err, "NewNode produced an unexpected error")
+	child, err := NewNode("child", "Child")
+	require.NoError(t, err, "NewNode produced an unexpected error")
+
+	g.AddEdge(parent, child, "references_via_description")
+	g.AddEdgeWithMetadata(parent, child, "references_via_description", EdgeMetadata{Context: "Recap", Timestamps: []int{90}, Chapters: []Chapter{{Title: "Black holes", Start: 195}}})
+	g.AddEdgeWithMetadata(parent, child, "references_via_description", EdgeMetadata{Context: "The full answer", Timestamps: []int{600}, Chapters: []Chapter{{Title: "Questions", Start: 2700}}})
+	g.AddEdgeWithMetadata(parent, child, "references_via_description", EdgeMetadata{Timestamps: []int{90}, Chapters: []Chapter{{Title: "Black holes", Start: 195}}})
+
+	edges := g.GetEdges()
+	require.Len(t, edges, 1, "expected every reference to the child to share an edge")
+	require.Equal(t, EdgeMetadata{
+		Context:    "Recap",
+		Timestamps: []int{90, 600},
+		Chapters:   []Chapter{{Title: "Black holes", Start: 195}, {Title: "Questions", Start: 2700}},
+	}, edges[0].GetMetadata(), "expected each timestamp and chapter once, and the first context")
+	require.Contains(t, g.ToDOT(), `"parent" -> "child" [label="references_via_description @ 1m30s, 10m0s", tooltip="Black holes\nQuestions\nRecap"];`)
+}
+
+func TestNewPlaceholderNode(t *testing.T) {
+	n, err := NewPlaceholderNode("YWxub2XhmXM", "http://www.youtube.com/watch?v=YWxub2XhmXM", StatusPrivate, "http://www.youtube.com/watch?v=YWxub2XhmXM")
+	require.NoError(t, err, "NewPlaceholderNode produced an unexpected error")
+	require.Equal(t, StatusPrivate, n.GetStatus())
+	require.JSONEq(t, `{"label":"http://www.youtube.com/watch?v=YWxub2XhmXM","id":"YWxub2XhmXM","metadata":{"id":"YWxub2XhmXM","status":"private","url":"http://www.youtube.com/watch?v=YWxub2XhmXM"}}`, n.ToJSON())
+
+	_, err = NewPlaceholderNode("YWxub2XhmXM", "label", "", "")
+	require.Error(t, err, "expected an empty status to be rejected")
+}
+
+func TestNewVideoNode(t *testing.T) {
+	n, err := NewVideoNode("iDIcydiQOhc", "New Results in Quantum Tunneling vs. The Speed of Light", VideoMetadata{
+		ChannelID:    "UC7_gcs09iThXybpVgjHZ_7g",
+		ChannelTitle: "PBS Space Time",
+		Duration:     "PT15M27S",
+		ViewCount:    462134,
+		WatchURL:     "https://www.youtube.com/watch?v=iDIcydiQOhc",
+	})
+	require.NoError(t, err, "NewVideoNode produced an unexpected error")
+
+	md, ok := n.GetVideoMetadata()
+	require.True(t, ok, "expected a video node to have video metadata")
+	require.Equal(t, "PBS Space Time", md.ChannelTitle)
+	require.JSONEq(t, `{"label":"New Results in Quantum Tunneling vs. The Speed of Light","id":"iDIcydiQOhc","metadata":{"id":"iDIcydiQOhc","channelId":"UC7_gcs09iThXybpVgjHZ_7g","channelTitle":"PBS Space Time","duration":"PT15M27S","viewCount":462134,"watchUrl":"https://www.youtube.com/watch?v=iDIcydiQOhc","depth":0}}`, n.ToJSON())
+
+	plain, err := NewNode("iDIcydiQOhc", "label")
+	require.NoError(t, err, "NewNode produced an unexpected error")
+	_, ok = plain.GetVideoMetadata()
+	require.False(t, ok, "expected a plain node to have no video metadata")
+}
+
+func TestGraph_ToDOT(t *testing.T) {
+	g := NewGraph("", "Youtube \"Video\" Dependencies", "ydg")
+	parent, err := NewVideoNode("iDIcydiQOhc", "New Results in Quantum Tunneling", VideoMetadata{ChannelID: "UC7_gcs09iThXybpVgjHZ_7g", ChannelTitle: "PBS Space Time"})
+	require.NoError(t, err, "NewVideoNode produced an unexpected error")
+	child, err := NewVideoNode("-IfmgyXs7z8", "Is Quantum Tunneling Faster than Light?\nPBS", VideoMetadata{ChannelID: "UC7_gcs09iThXybpVgjHZ_7g", ChannelTitle: "PBS Space Time"})
+	require.NoError(t, err, "NewVideoNode produced an unexpected error")
+	broken, err := NewPlaceholderNode("YWxub2XhmXM", `C:\path`, StatusPrivate, "")
+	require.NoError(t, err, "NewPlaceholderNode produced an unexpected error")
+
+	g.AddEdge(parent, child, "references_via_description")
+	g.AddEdge(parent, broken, "references_via_description")
+
+	expected := `digraph "Youtube \"Video\" Dependencies" {
+    label="Youtube \"Video\" Dependencies";
+    subgraph "cluster_UC7_gcs09iThXybpVgjHZ_7g" {
+        label="PBS Space Time";
+        "-IfmgyXs7z8" [label="Is Quantum Tunneling Faster than Light?\nPBS"];
+        "iDIcydiQOhc" [label="New Results in Quantum Tunneling"];
+    }
+    "YWxub2XhmXM" [label="[private] C:\\path", style=dashed];
+    "iDIcydiQOhc" -> "-IfmgyXs7z8" [label="references_via_description"];
+    "iDIcydiQOhc" -> "YWxub2XhmXM" [label="references_via_description"];
+}
+`
+	require.Equal(t, expected, g.ToDOT())
+}
+
+func TestAggregateByChannel(t *testing.T) {
+	g := NewGraph("", "Youtube Video Dependencies", "ydg")
+	video := func(id, channelID, channelTitle string) Node {
+		n, err := NewVideoNode(id, id, VideoMetadata{ChannelID: channelID, ChannelTitle: channelTitle})
+		require.NoError(t, err, "NewVideoNode produced an unexpected error")
+		return n
+	}
+	a1 := video("aaaaaaaaaa1", "UCa", "Channel A")
+	a2 := video("aaaaaaaaaa2", "UCa", "Channel A")
+	b1 := video("bbbbbbbbbb1", "UCb", "Channel B")
+	b2 := video("bbbbbbbbbb2", "UCb", "Channel B")
+	broken, err := NewPlaceholderNode("deleted0001", "deleted0001", StatusDeleted, "")
+	require.NoError(t, err, "NewPlaceholderNode produced an unexpected error")
+
+	g.AddEdge(a1, a2, "references_via_description")
+	g.AddEdge(a1, b1, "references_via_description")
+	g.AddEdge(a2, b1, "references_via_description")
+	g.AddEdge(a2, b2, "references_via_description")
+	g.AddEdge(b1, broken, "references_via_description")
+
+	agg := AggregateByChannel(g)
+	nodes := agg.GetNodes()
+	require.Len(t, nodes, 2, "expected a node per channel, leaving out placeholders")
+	md, ok := nodes[0].GetChannelMetadata()
+	require.True(t, ok, "expected a channel node to have channel metadata")
+	require.Equal(t, ChannelMetadata{VideoCount: 2, URL: "https://www.youtube.com/channel/UCa"}, md)
+
+	edges := agg.GetEdges()
+	require.Len(t, edges, 2)
+	require.Equal(t, "UCa", edges[0].GetSource())
+	require.Equal(t, "UCb", edges[0].GetTarget())
+	require.Equal(t, 3, edges[0].GetMetadata().Weight, "expected the heaviest edge first")
+	require.Equal(t, "UCa", edges[1].GetTarget(), "expected references within a channel to be kept as a loop")
+	require.Equal(t, 1, edges[1].GetMetadata().Weight)
+
+	for _, format := range Formats {
+		_, err := Encode(agg, format)
+		require.NoError(t, err, "Encode produced an unexpected error for format %s", format)
+	}
+
+	same, err := Aggregate(g, AggregateNone)
+	require.NoError(t, err, "Aggregate produced an unexpected error")
+	require.Equal(t, g, same, "expected no aggregation to return the graph itself")
+	_, err = Aggregate(g, "planet")
+	require.Error(t, err, "expected an unsupported aggregation to fail")
+}
+
+func TestNewWatchOrder(t *testing.T) {
+	g := NewGraph("", "Youtube Video Dependencies", "ydg")
+	video := func(id, publishedAt, duration string) Node {
+		n, err := NewVideoNode(id, "Title of "+id, VideoMetadata{PublishedAt: publishedAt, Duration: duration, WatchURL: "https://www.youtube.com/watch?v=" + id})
+		require.NoError(t, err, "NewVideoNode produced an unexpected error")
+		return n
+	}
+	finale := video("finale00001", "2021-03-05T17:00:00Z", "PT15M27S")
+	sequel := video("sequel00001", "2021-02-05T17:00:00Z", "PT12M45S")
+	pilot := video("pilot000001", "2021-01-05T17:00:00Z", "PT1H5M")
+	lecture := video("lecture0001", "2020-11-20T12:30:00Z", "")
+	aside := video("aside000001", "2019-06-01T09:00:00Z", "PT7M")
+	broken, err := NewPlaceholderNode("deleted0001", "deleted0001", StatusDeleted, "")
+	require.NoError(t, err, "NewPlaceholderNode produced an unexpected error")
+	playlist, err := NewPlaylistNode("PLseries000000000001", "Series", PlaylistMetadata{})
+	require.NoError(t, err, "NewPlaylistNode produced an unexpected error")
+
+	// The finale and sequel reference each other, and the pilot is only reached through a playlist
+	g.AddEdge(finale, sequel, "references_via_description")
+	g.AddEdge(sequel, finale, "references_sequel")
+	g.AddEdge(sequel, playlist, "references_via_description")
+	g.AddEdge(playlist, pilot, "contains")
+	g.AddEdge(pilot, lecture, "references_via_description")
+	g.AddEdge(finale, broken, "references_via_description")
+	g.AddNode(aside)
+
+	order := NewWatchOrder(g)
+	ids := []string{}
+	for _, item := range order.Items {
+		ids = append(ids, item.ID)
+	}
+	require.Equal(t, []string{"aside000001", "lecture0001", "pilot000001", "sequel00001", "finale00001"}, ids, "expected references first, breaking ties and cycles by publish date")
+	require.Equal(t, 1, order.Cycles)
+	require.Equal(t, 1, order.Items[3].Cycle)
+	require.Equal(t, 1, order.Items[4].Cycle)
+	require.Zero(t, order.Items[2].Cycle, "expected a video outside a cycle to have no cycle")
+	require.Equal(t, int64(420+3900+765+927), order.TotalSeconds)
+	require.Equal(t, 1, order.UnknownDurations)
+
+	expected := `1. Title of aside000001 (7:00)
+2. Title of lecture0001 (?:??)
+3. Title of pilot000001 (1:05:00)
+4. Title of sequel00001 (12:45) [cycle 1]
+5. Title of finale00001 (15:27) [cycle 1]
+
+Videos in the same cycle reference each other, and are listed by publish date.
+
+Total watch time: 1:40:12 (5 videos, 1 of unknown length)`
+	require.Equal(t, expected, order.ToText())
+
+	for _, format := range WatchFormats {
+		_, err := EncodeWatchOrder(order, format)
+		require.NoError(t, err, "EncodeWatchOrder produced an unexpected error for format %s", format)
+	}
+	_, err = EncodeWatchOrder(order, "dot")
+	require.Error(t, err, "expected an unsupported format to fail")
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		known    bool
+	}{
+		{"PT15M27S", 927, true},
+		{"PT1H5M", 3900, true},
+		{"PT7M", 420, true},
+		{"P1DT2H", 93600, true},
+		{"P0D", 0, true},
+		{"", 0, false},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"15:27", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			seconds, known := parseISODuration(tt.input)
+			require.Equal(t, tt.known, known)
+			require.Equal(t, tt.expected, seconds)
+		})
+	}
+}
@@ -6,10 +6,24 @@ import (
 	"strings"
 )
 
+// Statuses of a placeholder node, describing why the object it stands in for could not be resolved.
+const (
+	StatusDeleted     = "deleted"
+	StatusPrivate     = "private"
+	StatusNotFound    = "not_found"
+	StatusParseError  = "parse_error"
+	StatusUnavailable = "unavailable"
+)
+
 // Node defines an interface for an object that can be inserted into a graph.
 type Node interface {
 	GetID() string
 	GetLabel() string
+	GetStatus() string
+	GetVideoMetadata() (VideoMetadata, bool)
+	GetPlaylistMetadata() (PlaylistMetadata, bool)
+	GetChannelMetadata() (ChannelMetadata, bool)
+	GetExternalMetadata() (ExternalMetadata, bool)
 	ToJSON() string
 }
 
@@ -34,6 +48,95 @@ type node struct {
 
 type nodeMetadata struct {
 	ID string `json:"id"`
+	// Status is only set for placeholder nodes
+	Status string `json:"status,omitempty"`
+	// URL is the original URL a placeholder node was created from
+	URL string `json:"url,omitempty"`
+	// VideoMetadata is only set for video nodes, and its fields are inlined in the metadata
+	*VideoMetadata
+	// Playlist is only set for playlist nodes
+	Playlist *PlaylistMetadata `json:"playlist,omitempty"`
+	// Channel is only set for the channel nodes of an aggregated graph
+	Channel *ChannelMetadata `json:"channel,omitempty"`
+	// External is only set for external nodes
+	External *ExternalMetadata `json:"external,omitempty"`
+}
+
+// VideoMetadata describes the video a node represents, allowing visualizers to color and size
+// nodes by channel, age, length or popularity.
+/*
+{
+    "channelId": "UC7_gcs09iThXybpVgjHZ_7g",
+    "channelTitle": "PBS Space Time",
+    "publishedAt": "2021-10-13T20:56:02Z",
+    "duration": "PT15M27S",
+    "viewCount": 462134,
+    "likeCount": 19231,
+    "thumbnailUrl": "https://i.ytimg.com/vi/iDIcydiQOhc/maxresdefault.jpg",
+    "watchUrl": "https://www.youtube.com/watch?v=iDIcydiQOhc",
+    "depth": 0
+}
+*/
+type VideoMetadata struct {
+	ChannelID    string `json:"channelId,omitempty"`
+	ChannelTitle string `json:"channelTitle,omitempty"`
+	PublishedAt  string `json:"publishedAt,omitempty"`
+	// Duration is an ISO 8601 duration, such as PT15M27S
+	Duration     string `json:"duration,omitempty"`
+	ViewCount    uint64 `json:"viewCount,omitempty"`
+	LikeCount    uint64 `json:"likeCount,omitempty"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+	WatchURL     string `json:"watchUrl,omitempty"`
+	// Depth is the shortest distance from the root of the graph to the video
+	Depth int `json:"depth"`
+}
+
+// PlaylistMetadata describes the playlist a node represents.
+/*
+{
+    "channelId": "UC7_gcs09iThXybpVgjHZ_7g",
+    "channelTitle": "PBS Space Time",
+    "itemCount": 12,
+    "url": "https://www.youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur",
+    "depth": 1
+}
+*/
+type PlaylistMetadata struct {
+	ChannelID    string `json:"channelId,omitempty"`
+	ChannelTitle string `json:"channelTitle,omitempty"`
+	ItemCount    int    `json:"itemCount"`
+	URL          string `json:"url,omitempty"`
+	// Depth is the shortest distance from the root of the graph to the playlist
+	Depth int `json:"depth"`
+}
+
+// ChannelMetadata describes the channel a node of an aggregated graph represents.
+/*
+{
+    "videoCount": 5,
+    "url": "https://www.youtube.com/channel/UC7_gcs09iThXybpVgjHZ_7g"
+}
+*/
+type ChannelMetadata struct {
+	// VideoCount is the number of the channel's videos in the graph that was aggregated
+	VideoCount int    `json:"videoCount"`
+	URL        string `json:"url,omitempty"`
+}
+
+// ExternalMetadata describes the object outside YouTube that an external node represents, such
+// as a paper or a shop.
+/*
+{
+    "kind": "doi",
+    "domain": "doi.org",
+    "url": "https://doi.org/10.1088/1367-2630/ab5c7d"
+}
+*/
+type ExternalMetadata struct {
+	// Kind is doi or arxiv for a paper, or link for anything else
+	Kind   string `json:"kind"`
+	Domain string `json:"domain"`
+	URL    string `json:"url"`
 }
 
 // NewNode creates an instance of node, which implements the Node interface.
@@ -52,6 +155,66 @@ func NewNode(id string, label string) (Node, error) {
 	}, nil
 }
 
+// NewPlaceholderNode creates a node standing in for an object that could not be resolved,
+// such as a deleted or private video, recording the reason as status along with the original url.
+func NewPlaceholderNode(id, label, status, url string) (Node, error) {
+	if strings.TrimSpace(status) == "" {
+		return &node{}, fmt.Errorf("placeholder node status cannot be empty")
+	}
+	n, err := NewNode(id, label)
+	if err != nil {
+		return n, err
+	}
+	placeholder := n.(*node)
+	placeholder.Metadata.Status = status
+	placeholder.Metadata.URL = url
+	return placeholder, nil
+}
+
+// NewVideoNode creates a node representing a video, described by md.
+func NewVideoNode(id, label string, md VideoMetadata) (Node, error) {
+	n, err := NewNode(id, label)
+	if err != nil {
+		return n, err
+	}
+	vn := n.(*node)
+	vn.Metadata.VideoMetadata = &md
+	return vn, nil
+}
+
+// NewPlaylistNode creates a node representing a playlist, described by md.
+func NewPlaylistNode(id, label string, md PlaylistMetadata) (Node, error) {
+	n, err := NewNode(id, label)
+	if err != nil {
+		return n, err
+	}
+	pn := n.(*node)
+	pn.Metadata.Playlist = &md
+	return pn, nil
+}
+
+// NewChannelNode creates a node representing a channel, described by md.
+func NewChannelNode(id, label string, md ChannelMetadata) (Node, error) {
+	n, err := NewNode(id, label)
+	if err != nil {
+		return n, err
+	}
+	cn := n.(*node)
+	cn.Metadata.Channel = &md
+	return cn, nil
+}
+
+// NewExternalNode creates a node representing an object outside YouTube, described by md.
+func NewExternalNode(id, label string, md ExternalMetadata) (Node, error) {
+	n, err := NewNode(id, label)
+	if err != nil {
+		return n, err
+	}
+	en := n.(*node)
+	en.Metadata.External = &md
+	return en, nil
+}
+
 // GetID returns the ID of the node, preferring Metadata.ID over ID, though the values are equivalent.
 func (n *node) GetID() string {
 	return n.Metadata.ID
@@ -62,6 +225,43 @@ func (n *node) GetLabel() string {
 	return n.Label
 }
 
+// GetStatus returns the status of a placeholder node, or an empty string for any other node.
+func (n *node) GetStatus() string {
+	return n.Metadata.Status
+}
+
+// GetVideoMetadata returns the metadata of a video node, and false for any other node.
+func (n *node) GetVideoMetadata() (VideoMetadata, bool) {
+	if n.Metadata.VideoMetadata == nil {
+		return VideoMetadata{}, false
+	}
+	return *n.Metadata.VideoMetadata, true
+}
+
+// GetPlaylistMetadata returns the metadata of a playlist node, and false for any other node.
+func (n *node) GetPlaylistMetadata() (PlaylistMetadata, bool) {
+	if n.Metadata.Playlist == nil {
+		return PlaylistMetadata{}, false
+	}
+	return *n.Metadata.Playlist, true
+}
+
+// GetChannelMetadata returns the metadata of a channel node, and false for any other node.
+func (n *node) GetChannelMetadata() (ChannelMetadata, bool) {
+	if n.Metadata.Channel == nil {
+		return ChannelMetadata{}, false
+	}
+	return *n.Metadata.Channel, true
+}
+
+// GetExternalMetadata returns the metadata of an external node, and false for any other node.
+func (n *node) GetExternalMetadata() (ExternalMetadata, bool) {
+	if n.Metadata.External == nil {
+		return ExternalMetadata{}, false
+	}
+	return *n.Metadata.External, true
+}
+
 // ToJSON returns a string representation of the node, following the json graph schema v2.
 func (n *node) ToJSON() string {
 	b, _ := json.Marshal(n)
@@ -0,0 +1,356 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats supported by EncodeWatchOrder.
+const (
+	WatchFormatText     = "text"
+	WatchFormatMarkdown = "markdown"
+	WatchFormatJSON     = "json"
+)
+
+// WatchFormats lists every output format supported by EncodeWatchOrder.
+var WatchFormats = []string{WatchFormatText, WatchFormatMarkdown, WatchFormatJSON}
+
+// isoDurationRegex matches the ISO 8601 durations of videos, such as PT15M27S or P1DT2H
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// WatchOrder is the order in which to watch the videos of a graph, so that every video is
+// watched after the videos it references.
+type WatchOrder struct {
+	Items []WatchItem `json:"items"`
+	// TotalSeconds is the sum of the durations of the items whose duration is known
+	TotalSeconds int64 `json:"totalSeconds"`
+	// UnknownDurations is the number of items whose duration is unknown
+	UnknownDurations int `json:"unknownDurations"`
+	// Cycles is the number of groups of videos that reference each other
+	Cycles int `json:"cycles"`
+}
+
+// WatchItem is a single video of a WatchOrder.
+type WatchItem struct {
+	// Position is the 1-based position of the video in the watch order
+	Position     int    `json:"position"`
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	ChannelTitle string `json:"channelTitle,omitempty"`
+	PublishedAt  string `json:"publishedAt,omitempty"`
+	// Duration is an ISO 8601 duration, such as PT15M27S
+	Duration string `json:"duration,omitempty"`
+	Seconds  int64  `json:"seconds"`
+	URL      string `json:"url,omitempty"`
+	// Cycle is the 1-based number of the group of videos referencing each other that the video
+	// belongs to, or 0 if it isn't part of a cycle
+	Cycle int `json:"cycle,omitempty"`
+}
+
+// component is a strongly connected component of a graph, whose nodes all reference each other.
+type component struct {
+	nodes []Node
+	// key is the earliest publish date among the videos of the component
+	key string
+}
+
+// NewWatchOrder returns the order in which to watch the videos of g, such that every video comes
+// after the videos it references, directly or through playlists. Videos that reference each
+// other, directly or not, form a cycle and are kept together, ordered by publish date. Whenever
+// the references allow more than one video (or cycle) to come next, the earliest published is
+// picked, and ties are broken by ID, so the order is deterministic. Nodes that aren't videos,
+// such as placeholders, playlists and external nodes, are left out of the order.
+func NewWatchOrder(g Graph) WatchOrder {
+	nodes := g.GetNodes()
+	index := map[string]int{}
+	for i, n := range nodes {
+		index[n.GetID()] = i
+	}
+
+	// An edge from a video to the video it references means the latter is watched first, so
+	// the edges are followed in reverse
+	next := make([][]int, len(nodes))
+	for _, e := range g.GetEdges() {
+		source, okSource := index[e.GetSource()]
+		target, okTarget := index[e.GetTarget()]
+		if okSource && okTarget && source != target {
+			next[target] = append(next[target], source)
+		}
+	}
+
+	components, componentOf := stronglyConnectedComponents(nodes, next)
+
+	// Kahn's algorithm over the graph of components, picking the earliest ready component each time
+	indegree := make([]int, len(components))
+	successors := make([]map[int]bool, len(components))
+	for i := range components {
+		successors[i] = map[int]bool{}
+	}
+	for from, targets := range next {
+		for _, to := range targets {
+			c, d := componentOf[from], componentOf[to]
+			if c != d && !successors[c][d] {
+				successors[c][d] = true
+				indegree[d]++
+			}
+		}
+	}
+	ready := []int{}
+	for i := range components {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := WatchOrder{Items: []WatchItem{}}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			return watchBefore(components[ready[i]].key, components[ready[i]].nodes[0].GetID(), components[ready[j]].key, components[ready[j]].nodes[0].GetID())
+		})
+		c := ready[0]
+		ready = ready[1:]
+
+		order.add(components[c].nodes)
+		for d := range successors[c] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	return order
+}
+
+// add appends the videos among nodes, which form a single component, to the order.
+func (o *WatchOrder) add(nodes []Node) {
+	items := []WatchItem{}
+	for _, n := range nodes {
+		md, ok := n.GetVideoMetadata()
+		if !ok {
+			continue
+		}
+		seconds, known := parseISODuration(md.Duration)
+		if !known {
+			o.UnknownDurations++
+		}
+		o.TotalSeconds += seconds
+		items = append(items, WatchItem{
+			ID:           n.GetID(),
+			Title:        n.GetLabel(),
+			ChannelTitle: md.ChannelTitle,
+			PublishedAt:  md.PublishedAt,
+			Duration:     md.Duration,
+			Seconds:      seconds,
+			URL:          md.WatchURL,
+		})
+	}
+
+	if len(items) > 1 {
+		o.Cycles++
+	}
+	for _, item := range items {
+		if len(items) > 1 {
+			item.Cycle = o.Cycles
+		}
+		item.Position = len(o.Items) + 1
+		o.Items = append(o.Items, item)
+	}
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the graph of nodes
+// whose edges are next, using Tarjan's algorithm, along with the component of each node. The
+// nodes of each component are sorted by publish date, then ID.
+func stronglyConnectedComponents(nodes []Node, next [][]int) ([]component, []int) {
+	componentOf := make([]int, len(nodes))
+	indexOf := make([]int, len(nodes))
+	lowlink := make([]int, len(nodes))
+	onStack := make([]bool, len(nodes))
+	for i := range nodes {
+		indexOf[i] = -1
+	}
+	stack := []int{}
+	components := []component{}
+	counter := 0
+
+	var connect func(v int)
+	connect = func(v int) {
+		indexOf[v] = counter
+		lowlink[v] = counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range next[v] {
+			if indexOf[w] == -1 {
+				connect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && indexOf[w] < lowlink[v] {
+				lowlink[v] = indexOf[w]
+			}
+		}
+
+		if lowlink[v] != indexOf[v] {
+			return
+		}
+		c := component{}
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			componentOf[w] = len(components)
+			c.nodes = append(c.nodes, nodes[w])
+			if w == v {
+				break
+			}
+		}
+		sort.Slice(c.nodes, func(i, j int) bool {
+			return watchBefore(publishedAt(c.nodes[i]), c.nodes[i].GetID(), publishedAt(c.nodes[j]), c.nodes[j].GetID())
+		})
+		c.key = publishedAt(c.nodes[0])
+		components = append(components, c)
+	}
+
+	for i := range nodes {
+		if indexOf[i] == -1 {
+			connect(i)
+		}
+	}
+	return components, componentOf
+}
+
+// watchBefore reports whether the video published at a, with the ID idA, is watched before the
+// video published at b, with the ID idB, when the references allow either. Publish dates are
+// RFC 3339 times in UTC, which sort like strings, and unknown publish dates come last.
+func watchBefore(a, idA, b, idB string) bool {
+	if a != b {
+		return b == "" || (a != "" && a < b)
+	}
+	return idA < idB
+}
+
+// publishedAt returns the publish date of the video n represents, or an empty string if n
+// isn't a video.
+func publishedAt(n Node) string {
+	md, ok := n.GetVideoMetadata()
+	if !ok {
+		return ""
+	}
+	return md.PublishedAt
+}
+
+// parseISODuration returns the number of seconds in the ISO 8601 duration d, and whether d
+// could be parsed.
+func parseISODuration(d string) (int64, bool) {
+	d = strings.ToUpper(strings.TrimSpace(d))
+	res := isoDurationRegex.FindStringSubmatch(d)
+	// The regex also matches the empty durations P and PT
+	if len(res) != 5 || d == "P" || strings.HasSuffix(d, "T") {
+		return 0, false
+	}
+	var seconds int64
+	for i, unit := range []int64{24 * 60 * 60, 60 * 60, 60, 1} {
+		if res[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(res[i+1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds += n * unit
+	}
+	return seconds, true
+}
+
+// formatSeconds returns seconds as a clock duration, such as 15:27 or 1:05:00.
+func formatSeconds(seconds int64) string {
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// itemDuration returns the duration of item as a clock duration, or ?:?? if it is unknown.
+func itemDuration(item WatchItem) string {
+	if _, known := parseISODuration(item.Duration); !known {
+		return "?:??"
+	}
+	return formatSeconds(item.Seconds)
+}
+
+// total returns the summary line of the order.
+func (o WatchOrder) total() string {
+	total := fmt.Sprintf("%s (%d videos", formatSeconds(o.TotalSeconds), len(o.Items))
+	if o.UnknownDurations > 0 {
+		total += fmt.Sprintf(", %d of unknown length", o.UnknownDurations)
+	}
+	return total + ")"
+}
+
+// ToText returns the order as a numbered list of titles and durations, followed by the total
+// watch time.
+func (o WatchOrder) ToText() string {
+	width := len(strconv.Itoa(len(o.Items)))
+	var b strings.Builder
+	for _, item := range o.Items {
+		fmt.Fprintf(&b, "%*d. %s (%s)", width, item.Position, item.Title, itemDuration(item))
+		if item.Cycle > 0 {
+			fmt.Fprintf(&b, " [cycle %d]", item.Cycle)
+		}
+		b.WriteString("\n")
+	}
+	if o.Cycles > 0 {
+		b.WriteString("\nVideos in the same cycle reference each other, and are listed by publish date.\n")
+	}
+	fmt.Fprintf(&b, "\nTotal watch time: %s", o.total())
+	return b.String()
+}
+
+// ToMarkdown returns the order as a Markdown numbered list of links to each video, followed by
+// the total watch time.
+func (o WatchOrder) ToMarkdown() string {
+	escaper := strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`)
+	var b strings.Builder
+	b.WriteString("# Watch order\n\n")
+	for _, item := range o.Items {
+		title := escaper.Replace(item.Title)
+		if item.URL != "" {
+			title = fmt.Sprintf("[%s](%s)", title, item.URL)
+		}
+		fmt.Fprintf(&b, "%d. %s (%s)", item.Position, title, itemDuration(item))
+		if item.Cycle > 0 {
+			fmt.Fprintf(&b, " *cycle %d*", item.Cycle)
+		}
+		b.WriteString("\n")
+	}
+	if o.Cycles > 0 {
+		b.WriteString("\nVideos in the same cycle reference each other, and are listed by publish date.\n")
+	}
+	fmt.Fprintf(&b, "\n**Total watch time:** %s", o.total())
+	return b.String()
+}
+
+// ToJSON returns the JSON representation of the order.
+func (o WatchOrder) ToJSON() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+// EncodeWatchOrder returns the string representation of o in the given format.
+func EncodeWatchOrder(o WatchOrder, format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case WatchFormatText:
+		return o.ToText(), nil
+	case WatchFormatMarkdown:
+		return o.ToMarkdown(), nil
+	case WatchFormatJSON:
+		return o.ToJSON(), nil
+	}
+	return "", fmt.Errorf("unsupported format %s; Must be one of %s", format, strings.Join(WatchFormats, ", "))
+}
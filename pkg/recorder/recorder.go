@@ -0,0 +1,282 @@
+// Package recorder provides an http.RoundTripper that records HTTP interactions to cassette
+// files and replays them later, allowing API calls to be reproduced deterministically and offline.
+package recorder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Modes in which a Recorder can operate.
+const (
+	// ModeOff passes every request through to the underlying transport
+	ModeOff = "off"
+	// ModeRecord passes every request through to the underlying transport, saving each response to a cassette
+	ModeRecord = "record"
+	// ModeReplay serves every request from the cassettes, never using the underlying transport
+	ModeReplay = "replay"
+)
+
+// Modes contains every supported mode.
+var Modes = []string{ModeOff, ModeRecord, ModeReplay}
+
+// ErrNotRecorded is returned when replaying a request for which no cassette was recorded.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// redacted replaces every secret in a recorded interaction.
+const redacted = "REDACTED"
+
+// defaultScrubbedParams are the query parameters removed from every recorded URL, as they
+// carry credentials. The Data API reads the API key from the key parameter.
+var defaultScrubbedParams = []string{"key"}
+
+// Interaction is a single recorded request and its response, as stored in a cassette. Request
+// headers are never recorded, as they may carry credentials.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying the requests made through it.
+type Recorder struct {
+	mode    string
+	dir     string
+	next    http.RoundTripper
+	params  []string
+	secrets []string
+}
+
+// Option configures optional behavior of the Recorder created by New.
+type Option func(r *Recorder)
+
+// WithTransport makes the requests that are not replayed using next rather than http.DefaultTransport.
+func WithTransport(next http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.next = next
+	}
+}
+
+// WithScrubbedParams removes the query parameters named params from every recorded URL, in
+// addition to the key parameter.
+func WithScrubbedParams(params ...string) Option {
+	return func(r *Recorder) {
+		r.params = append(r.params, params...)
+	}
+}
+
+// WithSecrets replaces every occurrence of secrets in the recorded URLs and bodies.
+func WithSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if secret != "" {
+				r.secrets = append(r.secrets, secret)
+			}
+		}
+	}
+}
+
+// New creates a Recorder operating in mode, storing its cassettes in dir.
+func New(mode, dir string, opts ...Option) (*Recorder, error) {
+	if !contains(Modes, mode) {
+		return nil, fmt.Errorf("unsupported mode %s; Must be one of %s", mode, strings.Join(Modes, ", "))
+	}
+	if mode != ModeOff && dir == "" {
+		return nil, fmt.Errorf("a cassette directory is required in mode %s", mode)
+	}
+
+	r := &Recorder{
+		mode:   mode,
+		dir:    dir,
+		next:   http.DefaultTransport,
+		params: append([]string{}, defaultScrubbedParams...),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == ModeRecord {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create cassette directory %s: %s", dir, err)
+		}
+	}
+	return r, nil
+}
+
+// Mode returns the mode the Recorder operates in.
+func (r *Recorder) Mode() string {
+	return r.mode
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeOff {
+		return r.next.RoundTrip(req)
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{
+		Method: req.Method,
+		URL:    r.scrubURL(req.URL),
+		Body:   r.scrub(string(body)),
+	}
+	path := filepath.Join(r.dir, cassetteName(recorded))
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded, path)
+	}
+	return r.record(req, recorded, path)
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request, path string) (*http.Response, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, recorded.Method, recorded.URL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cassette %s: %s", path, err)
+	}
+
+	interaction := Interaction{}
+	err = json.Unmarshal(b, &interaction)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal cassette %s: %s", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request, path string) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		// Failures to reach the server aren't recorded, they are reproduced with a missing cassette
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body of %s %s: %s", recorded.Method, recorded.URL, err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       r.scrub(string(body)),
+		},
+	}
+	// The response is decoded by the caller before it is recorded, so the encoding no longer applies
+	interaction.Response.Header.Del("Content-Encoding")
+	interaction.Response.Header.Del("Content-Length")
+	interaction.Response.Header.Del("Set-Cookie")
+
+	b, err := json.MarshalIndent(interaction, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal cassette %s: %s", path, err)
+	}
+
+	// Written to a temporary file first, so that a concurrent replay never reads a partial cassette
+	tmp, err := ioutil.TempFile(r.dir, ".cassette-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cassette %s: %s", path, err)
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("unable to write cassette %s: %s", path, err)
+	}
+	return resp, nil
+}
+
+// scrubURL returns u without the scrubbed query parameters, with its remaining parameters
+// sorted so that equivalent requests are recorded identically.
+func (r *Recorder) scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for _, param := range r.params {
+		query.Del(param)
+	}
+	scrubbed.RawQuery = query.Encode()
+	return r.scrub(scrubbed.String())
+}
+
+// scrub replaces every secret in s.
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// cassetteName returns the name of the cassette recording req, derived from its method, URL and body.
+func cassetteName(req Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL + "\n" + req.Body))
+	return hex.EncodeToString(sum[:])[:16] + ".json"
+}
+
+// readBody reads the body of req, replacing it so that it can be read again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request body: %s", err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
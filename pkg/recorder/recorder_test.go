@@ -0,0 +1,75 @@
+package recorder
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err, "unable to read response body")
+	return resp.StatusCode, string(body), nil
+}
+
+func TestRecorder_RecordReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Equal(t, "s3cr3t", r.URL.Query().Get("key"), "expected the key to reach the server")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","echo":"s3cr3t"}`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	rec, err := New(ModeRecord, dir, WithSecrets("s3cr3t"))
+	require.NoError(t, err, "New produced an unexpected error")
+	status, body, err := get(t, &http.Client{Transport: rec}, server.URL+"/videos?key=s3cr3t&id=abc&part=id")
+	require.NoError(t, err, "recording produced an unexpected error")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `{"path":"/videos","echo":"s3cr3t"}`, body, "expected the recorded response to be unaltered")
+
+	cassettes, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err, "unable to list cassettes")
+	require.Len(t, cassettes, 1)
+	b, err := ioutil.ReadFile(cassettes[0])
+	require.NoError(t, err, "unable to read cassette")
+	require.NotContains(t, string(b), "s3cr3t", "expected the secret to be scrubbed from the cassette")
+	require.NotContains(t, string(b), "key=", "expected the key parameter to be scrubbed from the cassette")
+
+	rec, err = New(ModeReplay, dir)
+	require.NoError(t, err, "New produced an unexpected error")
+	client := &http.Client{Transport: rec}
+
+	// The key and the order of the parameters don't affect which cassette is replayed
+	status, body, err = get(t, client, server.URL+"/videos?part=id&id=abc&key=another")
+	require.NoError(t, err, "replaying produced an unexpected error")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `{"path":"/videos","echo":"REDACTED"}`, body)
+	require.Equal(t, 1, calls, "expected the replayed request not to reach the server")
+
+	_, _, err = get(t, client, server.URL+"/videos?part=id&id=xyz")
+	require.ErrorIs(t, err, ErrNotRecorded)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New("rewind", t.TempDir())
+	require.Error(t, err, "expected an unsupported mode to be rejected")
+
+	_, err = New(ModeReplay, "")
+	require.Error(t, err, "expected a missing cassette directory to be rejected")
+
+	rec, err := New(ModeOff, "")
+	require.NoError(t, err, "New produced an unexpected error")
+	require.Equal(t, ModeOff, rec.Mode())
+}
@@ -0,0 +1,212 @@
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxHops is the number of redirects followed before a link is given up on
+	DefaultMaxHops = 5
+	// DefaultTimeout is the timeout of each request made while following a link
+	DefaultTimeout = 10 * time.Second
+	userAgent      = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.81 Safari/537.36"
+)
+
+// DefaultAllowedHosts are the link shorteners whose links are followed unless WithAllowedHosts is given.
+var DefaultAllowedHosts = []string{
+	"bit.ly",
+	"buff.ly",
+	"cutt.ly",
+	"goo.gl",
+	"is.gd",
+	"ow.ly",
+	"rebrand.ly",
+	"t.co",
+	"tinyurl.com",
+}
+
+// Errors returned by the Resolver, so callers can check for them with errors.Is.
+var (
+	ErrHostNotAllowed   = errors.New("host not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrUnresolved       = errors.New("unable to resolve link")
+)
+
+// Resolver follows the HTTP redirects of links, such as those of link shorteners, to the link
+// they land on.
+type Resolver interface {
+	// Resolve returns the link that link redirects to. Only hosts on the allowlist are requested,
+	// so the first redirect to any other host, such as www.youtube.com, is where link lands.
+	Resolve(link string) (string, error)
+}
+
+// Option configures optional behavior of the Resolver created by New.
+type Option func(r *resolver)
+
+// WithHTTPClient makes the requests using client. Redirects are never followed by client itself.
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *resolver) {
+		c := *client
+		r.client = &c
+	}
+}
+
+// WithMaxHops gives up on links redirecting more than maxHops times.
+func WithMaxHops(maxHops int) Option {
+	return func(r *resolver) {
+		r.maxHops = maxHops
+	}
+}
+
+// WithAllowedHosts only requests links to hosts, or their subdomains, instead of DefaultAllowedHosts.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(r *resolver) {
+		r.allowed = map[string]bool{}
+		for _, host := range hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host != "" {
+				r.allowed[host] = true
+			}
+		}
+	}
+}
+
+func New(opts ...Option) Resolver {
+	r := &resolver{
+		client:  &http.Client{Timeout: DefaultTimeout},
+		maxHops: DefaultMaxHops,
+		cache:   map[string]result{},
+	}
+	WithAllowedHosts(DefaultAllowedHosts...)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+	// Each redirect is followed by Resolve, so that the host of every hop is checked
+	r.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return r
+}
+
+// result is the outcome of resolving a single link.
+type result struct {
+	target string
+	err    error
+}
+
+type resolver struct {
+	client  *http.Client
+	maxHops int
+	allowed map[string]bool
+	// cache contains the result of every link resolved so far, keyed by link
+	cache map[string]result
+	mu    sync.Mutex
+}
+
+// Resolve returns the link that link redirects to, following at most the configured number of
+// redirects. The result is cached, so each link is only followed once.
+func (r *resolver) Resolve(link string) (string, error) {
+	r.mu.Lock()
+	res, ok := r.cache[link]
+	r.mu.Unlock()
+	if ok {
+		return res.target, res.err
+	}
+
+	target, err := r.follow(link)
+
+	r.mu.Lock()
+	r.cache[link] = result{target: target, err: err}
+	r.mu.Unlock()
+	return target, err
+}
+
+// follow requests link, and each link it redirects to, until a response isn't a redirect or a
+// redirect leaves the allowed hosts.
+func (r *resolver) follow(link string) (string, error) {
+	current, err := neturl.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("%w: unable to parse link %s: %s", ErrUnresolved, link, err)
+	}
+	if !r.isAllowed(current) {
+		return "", fmt.Errorf("%w: %s", ErrHostNotAllowed, current.Hostname())
+	}
+
+	for hops := 0; ; hops++ {
+		location, err := r.next(current)
+		if err != nil {
+			return "", err
+		}
+		if location == nil {
+			return current.String(), nil
+		}
+		if hops == r.maxHops {
+			return "", fmt.Errorf("%w: %s redirected more than %d times", ErrTooManyRedirects, link, r.maxHops)
+		}
+
+		current = location
+		if !r.isAllowed(current) {
+			return current.String(), nil
+		}
+	}
+}
+
+// next requests u, returning the link it redirects to, or nil if it doesn't redirect. Hosts
+// that refuse HEAD requests are requested again using GET.
+func (r *resolver) next(u *neturl.URL) (*neturl.URL, error) {
+	resp, err := r.request(http.MethodHead, u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = r.request(http.MethodGet, u)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to request %s: %s", ErrUnresolved, u, err)
+	}
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s returned %d without a valid Location: %s", ErrUnresolved, u, resp.StatusCode, err)
+		}
+		return location, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: %s returned %d", ErrUnresolved, u, resp.StatusCode)
+}
+
+// request makes a single request to u without reading its body.
+func (r *resolver) request(method string, u *neturl.URL) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// isAllowed returns true if u is an http or https link to an allowed host, or a subdomain of one.
+func (r *resolver) isAllowed(u *neturl.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for allowed := range r.allowed {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package resolve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newRedirectServer serves links redirecting to YouTube, along with a function counting the
+// requests made for each path. /short redirects to /hop, which redirects to a video, /loop/n
+// redirects to /loop/n+1 forever, /page doesn't redirect, /head only redirects GET requests and
+// /gone is not found.
+func newRedirectServer(t *testing.T) (*httptest.Server, func(path string) int) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	mux := http.NewServeMux()
+	count := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path]++
+	}
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		http.Redirect(w, r, "/hop", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/hop", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		http.Redirect(w, r, "https://www.youtube.com/watch?v=iDIcydiQOhc", http.StatusFound)
+	})
+	mux.HandleFunc("/loop/", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		n, _ := strconv.Atoi(r.URL.Path[len("/loop/"):])
+		http.Redirect(w, r, "/loop/"+strconv.Itoa(n+1), http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/head", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "https://youtu.be/-IfmgyXs7z8", http.StatusFound)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+}
+
+func TestResolve(t *testing.T) {
+	server, requests := newRedirectServer(t)
+	r := New(WithHTTPClient(server.Client()), WithAllowedHosts("127.0.0.1"), WithMaxHops(3))
+
+	target, err := r.Resolve(server.URL + "/short")
+	require.NoError(t, err, "Resolve produced an unexpected error")
+	require.Equal(t, "https://www.youtube.com/watch?v=iDIcydiQOhc", target, "expected the first link off the allowed hosts to be the target")
+
+	target, err = r.Resolve(server.URL + "/page")
+	require.NoError(t, err, "Resolve produced an unexpected error")
+	require.Equal(t, server.URL+"/page", target, "expected a link that doesn't redirect to be its own target")
+
+	target, err = r.Resolve(server.URL + "/head")
+	require.NoError(t, err, "Resolve produced an unexpected error")
+	require.Equal(t, "https://youtu.be/-IfmgyXs7z8", target, "expected a host refusing HEAD to be requested using GET")
+	require.Equal(t, 2, requests("/head"))
+}
+
+func TestResolve_Errors(t *testing.T) {
+	server, requests := newRedirectServer(t)
+	r := New(WithHTTPClient(server.Client()), WithAllowedHosts("127.0.0.1"), WithMaxHops(3))
+
+	_, err := r.Resolve(server.URL + "/loop/0")
+	require.ErrorIs(t, err, ErrTooManyRedirects)
+	require.Equal(t, 4, requests("/loop/0")+requests("/loop/1")+requests("/loop/2")+requests("/loop/3")+requests("/loop/4"), "expected the hop limit to stop the requests")
+
+	_, err = r.Resolve(server.URL + "/gone")
+	require.ErrorIs(t, err, ErrUnresolved)
+
+	_, err = r.Resolve("https://example.com/short")
+	require.ErrorIs(t, err, ErrHostNotAllowed)
+
+	_, err = New(WithHTTPClient(server.Client())).Resolve(server.URL + "/short")
+	require.ErrorIs(t, err, ErrHostNotAllowed, "expected the test server to be left off the default allowlist")
+	require.Equal(t, 0, requests("/short"))
+}
+
+func TestResolve_Cache(t *testing.T) {
+	server, requests := newRedirectServer(t)
+	r := New(WithHTTPClient(server.Client()), WithAllowedHosts("127.0.0.1"))
+
+	for i := 0; i < 3; i++ {
+		target, err := r.Resolve(server.URL + "/short")
+		require.NoError(t, err, "Resolve produced an unexpected error")
+		require.Equal(t, "https://www.youtube.com/watch?v=iDIcydiQOhc", target)
+
+		_, err = r.Resolve(server.URL + "/gone")
+		require.ErrorIs(t, err, ErrUnresolved)
+	}
+	require.Equal(t, 1, requests("/short"), "expected each link to be followed once")
+	require.Equal(t, 1, requests("/hop"))
+	require.Equal(t, 1, requests("/gone"), "expected failures to be cached too")
+}
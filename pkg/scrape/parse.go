@@ -0,0 +1,274 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ytapi "google.golang.org/api/youtube/v3"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+const (
+	playerResponseVar = "ytInitialPlayerResponse"
+	initialDataVar    = "ytInitialData"
+
+	playabilityOK = "OK"
+)
+
+var (
+	// likesRegex matches the accessibility label of the like button, such as "19,231 likes"
+	likesRegex = regexp.MustCompile(`^([\d,.]+) likes?`)
+)
+
+// playerResponse is the subset of ytInitialPlayerResponse describing the video.
+type playerResponse struct {
+	PlayabilityStatus struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	} `json:"playabilityStatus"`
+	VideoDetails struct {
+		VideoID          string `json:"videoId"`
+		Title            string `json:"title"`
+		LengthSeconds    string `json:"lengthSeconds"`
+		ChannelID        string `json:"channelId"`
+		ShortDescription string `json:"shortDescription"`
+		ViewCount        string `json:"viewCount"`
+		Author           string `json:"author"`
+		Thumbnail        struct {
+			Thumbnails []thumbnail `json:"thumbnails"`
+		} `json:"thumbnail"`
+	} `json:"videoDetails"`
+	Microformat struct {
+		PlayerMicroformatRenderer struct {
+			PublishDate string `json:"publishDate"`
+			UploadDate  string `json:"uploadDate"`
+			Embed       struct {
+				IframeURL string `json:"iframeUrl"`
+				Width     int64  `json:"width"`
+				Height    int64  `json:"height"`
+			} `json:"embed"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+}
+
+type thumbnail struct {
+	URL    string `json:"url"`
+	Width  int64  `json:"width"`
+	Height int64  `json:"height"`
+}
+
+// Parse returns the video described by the ytInitialPlayerResponse and ytInitialData
+// embedded in the HTML of a watch page.
+func Parse(r io.Reader) (youtube.Video, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read watch page: %s", err)
+	}
+	page := string(b)
+
+	player := playerResponse{}
+	err = extractJSON(page, playerResponseVar, &player)
+	if err != nil {
+		return nil, err
+	}
+
+	details := player.VideoDetails
+	if player.PlayabilityStatus.Status != playabilityOK && details.VideoID == "" {
+		return nil, playabilityError(player.PlayabilityStatus.Status, player.PlayabilityStatus.Reason)
+	}
+	if details.VideoID == "" {
+		return nil, fmt.Errorf("%w: %s does not describe a video", youtube.ErrVideoNotFound, playerResponseVar)
+	}
+
+	// ytInitialData is only needed for details missing from the player response, so a page
+	// without it can still be parsed
+	var initialData interface{}
+	err = extractJSON(page, initialDataVar, &initialData)
+	if err != nil {
+		initialData = nil
+	}
+
+	micro := player.Microformat.PlayerMicroformatRenderer
+	vid := &ytapi.Video{
+		Id:   details.VideoID,
+		Kind: "youtube#video",
+		Snippet: &ytapi.VideoSnippet{
+			Title:        details.Title,
+			Description:  details.ShortDescription,
+			ChannelId:    details.ChannelID,
+			ChannelTitle: details.Author,
+			PublishedAt:  publishedAt(micro.PublishDate, micro.UploadDate),
+			Thumbnails:   thumbnails(details.Thumbnail.Thumbnails),
+		},
+		ContentDetails: &ytapi.VideoContentDetails{
+			Duration: isoDuration(details.LengthSeconds),
+		},
+		Statistics: &ytapi.VideoStatistics{
+			ViewCount: parseCount(details.ViewCount),
+			LikeCount: likeCount(initialData),
+		},
+	}
+	if micro.Embed.IframeURL != "" {
+		vid.Player = &ytapi.VideoPlayer{
+			EmbedHtml:   fmt.Sprintf(`<iframe width="%d" height="%d" src="%s" frameborder="0" allowfullscreen></iframe>`, micro.Embed.Width, micro.Embed.Height, micro.Embed.IframeURL),
+			EmbedWidth:  micro.Embed.Width,
+			EmbedHeight: micro.Embed.Height,
+		}
+	}
+
+	return youtube.NewVideo(vid), nil
+}
+
+// extractJSON decodes the JSON object assigned to the named variable in page into v.
+func extractJSON(page, name string, v interface{}) error {
+	start := -1
+	for _, prefix := range []string{"var " + name + " = ", name + " = ", `window["` + name + `"] = `} {
+		if i := strings.Index(page, prefix); i >= 0 {
+			start = i + len(prefix)
+			break
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("watch page does not contain %s", name)
+	}
+
+	// The decoder stops after the first complete value, ignoring the script that follows it
+	err := json.NewDecoder(strings.NewReader(page[start:])).Decode(v)
+	if err != nil {
+		return fmt.Errorf("unable to decode %s: %s", name, err)
+	}
+	return nil
+}
+
+// playabilityError returns the typed error matching the playability status of a video that can't be watched.
+func playabilityError(status, reason string) error {
+	lower := strings.ToLower(reason)
+	switch {
+	case strings.Contains(lower, "private"):
+		return fmt.Errorf("%w: %s: %s", youtube.ErrPrivateVideo, status, reason)
+	case strings.Contains(lower, "removed"), strings.Contains(lower, "terminated"), strings.Contains(lower, "deleted"):
+		return fmt.Errorf("%w: %s: %s", youtube.ErrVideoDeleted, status, reason)
+	}
+	return fmt.Errorf("%w: %s: %s", youtube.ErrVideoNotFound, status, reason)
+}
+
+// publishedAt returns the publish date of the video in RFC 3339 format, which the watch page
+// provides either as a full timestamp or as a date alone.
+func publishedAt(dates ...string) string {
+	for _, date := range dates {
+		if date == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, date); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+		if t, err := time.Parse("2006-01-02", date); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return ""
+}
+
+// thumbnails returns the thumbnails of the watch page, the largest of which is used as maxres.
+func thumbnails(thumbs []thumbnail) *ytapi.ThumbnailDetails {
+	details := &ytapi.ThumbnailDetails{}
+	var largest *thumbnail
+	for i := range thumbs {
+		if largest == nil || thumbs[i].Width > largest.Width {
+			largest = &thumbs[i]
+		}
+	}
+	if largest != nil {
+		details.Maxres = &ytapi.Thumbnail{Url: largest.URL, Width: largest.Width, Height: largest.Height}
+	}
+	return details
+}
+
+// isoDuration converts a length in seconds to an ISO 8601 duration, such as PT15M27S.
+func isoDuration(lengthSeconds string) string {
+	seconds, err := strconv.ParseInt(lengthSeconds, 10, 64)
+	if err != nil || seconds < 0 {
+		return ""
+	}
+	d := "PT"
+	if h := seconds / 3600; h > 0 {
+		d += fmt.Sprintf("%dH", h)
+	}
+	if m := seconds % 3600 / 60; m > 0 {
+		d += fmt.Sprintf("%dM", m)
+	}
+	if s := seconds % 60; s > 0 || seconds == 0 {
+		d += fmt.Sprintf("%dS", s)
+	}
+	return d
+}
+
+// parseCount parses a count that may contain thousands separators, such as 19,231.
+func parseCount(s string) uint64 {
+	n, err := strconv.ParseUint(strings.NewReplacer(",", "", ".", "").Replace(strings.TrimSpace(s)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// likeCount searches ytInitialData for the accessibility label of the like button within
+// the videoPrimaryInfoRenderer, returning 0 if it can't be found.
+func likeCount(initialData interface{}) uint64 {
+	primary := findKey(initialData, "videoPrimaryInfoRenderer")
+	if primary == nil {
+		return 0
+	}
+	var count uint64
+	walk(primary, func(key string, value interface{}) bool {
+		label, ok := value.(string)
+		if key != "label" || !ok {
+			return true
+		}
+		if match := likesRegex.FindStringSubmatch(label); match != nil {
+			count = parseCount(match[1])
+			return false
+		}
+		return true
+	})
+	return count
+}
+
+// findKey returns the first value stored under key anywhere within v.
+func findKey(v interface{}, key string) interface{} {
+	var found interface{}
+	walk(v, func(k string, value interface{}) bool {
+		if k == key {
+			found = value
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// walk calls fn for every key and value within v, depth-first, until fn returns false.
+func walk(v interface{}, fn func(key string, value interface{}) bool) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			if !fn(k, value) || !walk(value, fn) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, value := range t {
+			if !walk(value, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -2,19 +2,90 @@ package scrape
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+const (
+	defaultBaseURL = "https://www.youtube.com"
+	watchPath      = "/watch?v=%s"
+	userAgent      = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.81 Safari/537.36"
 )
 
+// Scraper creates videos from YouTube watch pages, without using the Data API.
 type Scraper interface {
-	Scrape(ytURL string) error
+	Scrape(ytURL string) (youtube.Video, error)
 }
 
-func New() Scraper {
-	return &scraper{}
+// Option configures optional behavior of the Scraper created by New.
+type Option func(s *scraper)
+
+// WithHTTPClient fetches watch pages using client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *scraper) {
+		s.client = client
+	}
 }
 
-type scraper struct{}
+// WithBaseURL fetches watch pages from baseURL rather than https://www.youtube.com.
+func WithBaseURL(baseURL string) Option {
+	return func(s *scraper) {
+		s.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func New(opts ...Option) Scraper {
+	s := &scraper{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: defaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type scraper struct {
+	client  *http.Client
+	baseURL string
+}
+
+// Scrape fetches the watch page of the video referenced by ytURL, which may be any URL or ID
+// accepted by youtube.NewURL, and parses the video out of it.
+func (s *scraper) Scrape(ytURL string) (youtube.Video, error) {
+	url, err := youtube.NewURL(ytURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new URL from raw url %s: %w", ytURL, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.baseURL+fmt.Sprintf(watchPath, url.GetID()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for video %s: %s", url.GetID(), err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Skip the cookie consent interstitial served to some regions
+	req.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+"})
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to fetch watch page for video %s: %s", youtube.ErrUnavailable, url.GetID(), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: watch page for video %s returned %d", youtube.ErrVideoNotFound, url.GetID(), resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: watch page for video %s returned %d", youtube.ErrRateLimited, url.GetID(), resp.StatusCode)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("%w: watch page for video %s returned %d", youtube.ErrUnavailable, url.GetID(), resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("watch page for video %s returned %d", url.GetID(), resp.StatusCode)
+	}
 
-func (s *scraper) Scrape(ytURL string) error {
-	fmt.Printf("Scraping %s\n", ytURL)
-	return nil
+	return Parse(resp.Body)
 }
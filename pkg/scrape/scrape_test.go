@@ -1,13 +1,88 @@
 package scrape
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
 )
 
-func TestScrape_ScrapeNoErr(t *testing.T) {
-	s := New()
-	err := s.Scrape("https://fake.url/ytLinkLol")
+// newFixtureServer serves the watch page fixture testdata/watch_<id>.html for /watch?v=<id>.
+func newFixtureServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := os.ReadFile(filepath.Join("testdata", "watch_"+r.URL.Query().Get("v")+".html"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrape_ScrapeFixture(t *testing.T) {
+	server := newFixtureServer(t)
+	s := New(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	vid, err := s.Scrape("https://youtu.be/iDIcydiQOhc")
 	require.NoError(t, err, "s.Scrape produced an unexpected error")
+	require.Equal(t, "iDIcydiQOhc", vid.GetID())
+	require.Equal(t, "New Results in Quantum Tunneling vs. The Speed of Light", vid.GetTitle())
+	require.Equal(t, "UC7_gcs09iThXybpVgjHZ_7g", vid.GetChannelID())
+	require.Equal(t, "PBS Space Time", vid.GetChannelTitle())
+	require.Equal(t, "2021-10-13T00:00:00Z", vid.GetPublishedAt())
+	require.Equal(t, "PT15M27S", vid.GetDuration())
+	require.Equal(t, uint64(462134), vid.GetViewCount())
+	require.Equal(t, uint64(19231), vid.GetLikeCount())
+	require.Equal(t, "https://i.ytimg.com/vi/iDIcydiQOhc/maxresdefault.jpg", vid.GetThumbnailURL())
+	require.Contains(t, vid.GetEmbedHTML(), "https://www.youtube.com/embed/iDIcydiQOhc")
+	require.Equal(t, []string{"https://youtu.be/-IfmgyXs7z8"}, vid.GetUrlsFromDescription())
+}
+
+func TestScrape_ScrapeUnavailable(t *testing.T) {
+	server := newFixtureServer(t)
+	s := New(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{"private", "private0000", youtube.ErrPrivateVideo},
+		{"removed", "removed0000", youtube.ErrVideoDeleted},
+		{"missing page", "missing0000", youtube.ErrVideoNotFound},
+		{"invalid url", "https://fake.url/ytLinkLol", youtube.ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Scrape(tt.input)
+			require.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "watch_iDIcydiQOhc.html"))
+	require.NoError(t, err, "unable to open fixture")
+	defer f.Close()
+
+	_, err = Parse(f)
+	require.NoError(t, err, "Parse produced an unexpected error")
+
+	_, err = Parse(http.NoBody)
+	require.Error(t, err, "expected a page without ytInitialPlayerResponse to fail")
+}
+
+func TestIsoDuration(t *testing.T) {
+	require.Equal(t, "PT0S", isoDuration("0"))
+	require.Equal(t, "PT59S", isoDuration("59"))
+	require.Equal(t, "PT1H", isoDuration("3600"))
+	require.Equal(t, "PT1H2M3S", isoDuration("3723"))
+	require.Equal(t, "", isoDuration("abc"))
 }
@@ -0,0 +1,64 @@
+package scrape
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/youtube"
+)
+
+// source is a youtube.Source backed by a Scraper, requiring no API key.
+type source struct {
+	scraper Scraper
+}
+
+// NewSource creates a youtube.Source that scrapes the watch page of each video with s.
+func NewSource(s Scraper) youtube.Source {
+	return &source{scraper: s}
+}
+
+func (src *source) GetVideoByTitle(title string) (youtube.Video, error) {
+	return nil, fmt.Errorf("%w: unable to search for title %s by scraping", youtube.ErrUnsupported, title)
+}
+
+func (src *source) GetVideoByURL(rawURL string) (youtube.Video, error) {
+	return src.scraper.Scrape(rawURL)
+}
+
+func (src *source) GetVideoByID(id string) (youtube.Video, error) {
+	return src.scraper.Scrape(id)
+}
+
+// GetVideosByIDs scrapes the watch page of each of the ids in turn. Videos that can't be
+// watched, such as private or deleted videos, are reported in a *youtube.MissingVideosError,
+// unless another error occurred.
+func (src *source) GetVideosByIDs(ids []string) (map[string]youtube.Video, error) {
+	videos := map[string]youtube.Video{}
+	missing := &youtube.MissingVideosError{Errors: map[string]error{}}
+	var firstErr error
+	for _, id := range ids {
+		if _, ok := videos[id]; ok {
+			continue
+		}
+		vid, err := src.scraper.Scrape(id)
+		if errors.Is(err, youtube.ErrVideoNotFound) || errors.Is(err, youtube.ErrPrivateVideo) || errors.Is(err, youtube.ErrVideoDeleted) {
+			missing.IDs = append(missing.IDs, id)
+			missing.Errors[id] = err
+			continue
+		} else if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		videos[id] = vid
+	}
+
+	if firstErr != nil {
+		return videos, firstErr
+	}
+	if len(missing.IDs) > 0 {
+		return videos, missing
+	}
+	return videos, nil
+}
@@ -0,0 +1,146 @@
+package youtube
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// channelIDRegex matches a channel ID, such as UC7_gcs09iThXybpVgjHZ_7g
+	channelIDRegex = `^UC[\w-]{22}$`
+
+	channelURLFormat = "https://www.youtube.com/channel/%s"
+)
+
+var (
+	channelIDRe = regexp.MustCompile(channelIDRegex)
+)
+
+// ChannelRef identifies a channel either by its ID or by its @handle.
+type ChannelRef struct {
+	ID     string
+	Handle string
+}
+
+func (r ChannelRef) String() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Handle
+}
+
+// ParseChannelRef parses a channel ID, an @handle, or a link to a channel by either of them,
+// such as https://www.youtube.com/channel/UC7_gcs09iThXybpVgjHZ_7g or https://www.youtube.com/@pbsspacetime.
+func ParseChannelRef(input string) (ChannelRef, error) {
+	ref, err := ParseReference(input)
+	if err != nil {
+		return ChannelRef{}, err
+	}
+	switch ref.Kind {
+	case KindChannel:
+		return ChannelRef{ID: ref.ID}, nil
+	case KindHandle:
+		return ChannelRef{Handle: ref.ID}, nil
+	}
+	return ChannelRef{}, fmt.Errorf("%w: Unable to parse channel ID or handle out of %s", ErrInvalidURL, input)
+}
+
+// Channel is a YouTube channel, along with the playlist of its uploads.
+type Channel interface {
+	GetID() string
+	GetTitle() string
+	GetUploadsPlaylistID() string
+	GetURL() string
+}
+
+// UploadsFilter limits the uploads returned by a ChannelSource.
+type UploadsFilter struct {
+	// PublishedAfter and PublishedBefore bound the publish time of the uploads, and are ignored when zero
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	// MaxVideos is the maximum number of uploads returned, the most recent first, where 0 is unlimited
+	MaxVideos int
+}
+
+// includes returns true if an upload published at the given time passes the filter.
+func (f UploadsFilter) includes(publishedAt time.Time) bool {
+	if !f.PublishedAfter.IsZero() && publishedAt.Before(f.PublishedAfter) {
+		return false
+	}
+	if !f.PublishedBefore.IsZero() && !publishedAt.Before(f.PublishedBefore) {
+		return false
+	}
+	return true
+}
+
+// full returns true once count uploads are enough to satisfy MaxVideos.
+func (f UploadsFilter) full(count int) bool {
+	return f.MaxVideos > 0 && count >= f.MaxVideos
+}
+
+// ChannelSource is implemented by any Source that is also able to look up channels and their uploads.
+type ChannelSource interface {
+	GetChannel(ref ChannelRef) (Channel, error)
+	// GetUploads returns the IDs of the videos uploaded to ch that pass filter, the most recent first
+	GetUploads(ch Channel, filter UploadsFilter) ([]string, error)
+}
+
+// GetChannel looks up the channel identified by ref using src, returning ErrUnsupported if src
+// is unable to look up channels.
+func GetChannel(src Source, ref ChannelRef) (Channel, error) {
+	cs, ok := src.(ChannelSource)
+	if !ok {
+		return &channel{}, fmt.Errorf("%w: unable to get channel %s", ErrUnsupported, ref)
+	}
+	return cs.GetChannel(ref)
+}
+
+// GetUploads looks up the uploads of ch using src, returning ErrUnsupported if src is unable to
+// look up channels.
+func GetUploads(src Source, ch Channel, filter UploadsFilter) ([]string, error) {
+	cs, ok := src.(ChannelSource)
+	if !ok {
+		return []string{}, fmt.Errorf("%w: unable to get uploads of channel %s", ErrUnsupported, ch.GetID())
+	}
+	return cs.GetUploads(ch, filter)
+}
+
+type channel struct {
+	id      string
+	title   string
+	uploads string
+}
+
+// NewChannel creates the Channel with the given id. If uploadsPlaylistID is empty, the ID of the
+// uploads playlist is derived from id.
+func NewChannel(id, title, uploadsPlaylistID string) Channel {
+	id = strings.TrimSpace(id)
+	if uploadsPlaylistID == "" && strings.HasPrefix(id, "UC") {
+		// The uploads playlist of channel UCxyz is UUxyz
+		uploadsPlaylistID = "UU" + strings.TrimPrefix(id, "UC")
+	}
+	return &channel{
+		id:      id,
+		title:   strings.TrimSpace(title),
+		uploads: uploadsPlaylistID,
+	}
+}
+
+func (c *channel) GetID() string {
+	return c.id
+}
+
+func (c *channel) GetTitle() string {
+	return c.title
+}
+
+func (c *channel) GetUploadsPlaylistID() string {
+	return c.uploads
+}
+
+// GetURL returns the canonical URL of the channel's page.
+func (c *channel) GetURL() string {
+	return fmt.Sprintf(channelURLFormat, c.id)
+}
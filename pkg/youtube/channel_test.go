@@ -0,0 +1,51 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelRef(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ChannelRef
+	}{
+		{"UC7_gcs09iThXybpVgjHZ_7g", ChannelRef{ID: "UC7_gcs09iThXybpVgjHZ_7g"}},
+		{"https://www.youtube.com/channel/UC7_gcs09iThXybpVgjHZ_7g", ChannelRef{ID: "UC7_gcs09iThXybpVgjHZ_7g"}},
+		{"youtube.com/channel/UC7_gcs09iThXybpVgjHZ_7g/videos", ChannelRef{ID: "UC7_gcs09iThXybpVgjHZ_7g"}},
+		{"@pbsspacetime", ChannelRef{Handle: "@pbsspacetime"}},
+		{"https://m.youtube.com/@pbsspacetime/featured", ChannelRef{Handle: "@pbsspacetime"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := ParseChannelRef(tt.input)
+			require.NoError(t, err, "ParseChannelRef produced an unexpected error")
+			require.Equal(t, tt.expected, ref)
+		})
+	}
+
+	for _, input := range []string{"pbsspacetime", "https://www.youtube.com/watch?v=iDIcydiQOhc", "UCtooshort"} {
+		_, err := ParseChannelRef(input)
+		require.ErrorIs(t, err, ErrInvalidURL, "expected %s to be rejected", input)
+	}
+}
+
+func TestNewChannel_DerivesUploadsPlaylist(t *testing.T) {
+	require.Equal(t, "UU7_gcs09iThXybpVgjHZ_7g", NewChannel("UC7_gcs09iThXybpVgjHZ_7g", "PBS Space Time", "").GetUploadsPlaylistID())
+}
+
+func TestUploadsFilter(t *testing.T) {
+	filter := UploadsFilter{
+		PublishedAfter:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		PublishedBefore: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		MaxVideos:       2,
+	}
+	require.True(t, filter.includes(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)), "expected PublishedAfter to be inclusive")
+	require.False(t, filter.includes(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)), "expected PublishedBefore to be exclusive")
+	require.False(t, filter.includes(time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)))
+	require.False(t, filter.full(1))
+	require.True(t, filter.full(2))
+	require.False(t, UploadsFilter{}.full(1000), "expected MaxVideos 0 to be unlimited")
+}
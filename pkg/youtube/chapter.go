@@ -0,0 +1,80 @@
+package youtube
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// chapterRegex matches a line of a chapter list, such as "03:15 Black holes" or "1:02:03 - Outro",
+	// capturing the hours, minutes, seconds and title
+	chapterRegex = `(?m)^[ \t]*(?:(\d{1,2}):)?(\d{1,2}):(\d{2})[ \t]*(?:[-–—:|][ \t]*)?(\S.*?)[ \t]*$`
+	// anyLinkRegex matches any link, so it can be left out of the text describing it, such as the title of a chapter
+	anyLinkRegex = `\S*(?:https?:\/\/|www\.|youtu\.be\/|youtube\.com\/)\S*`
+)
+
+var (
+	chapterRe   = regexp.MustCompile(chapterRegex)
+	anyLinkRe   = regexp.MustCompile(anyLinkRegex)
+	blankLineRe = regexp.MustCompile(`\n[ \t]*\n`)
+)
+
+// Chapter is a section of a video, as listed in its description.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	// offset is the position of the chapter's line in the text it was parsed from
+	offset int
+	// end is the position in the text where the chapter's span ends, either at the next chapter's
+	// line or, for the last chapter of a list, at the end of its own line
+	end int
+}
+
+// ParseChapters returns the chapters listed in text, one per line starting with a timestamp,
+// in the order they appear. A chapter spans its own line and any lines up to the next chapter
+// of the same list, which ends at the first blank line, so the paragraphs following a list
+// don't belong to its last chapter.
+func ParseChapters(text string) []Chapter {
+	chapters := []Chapter{}
+	for _, match := range chapterRe.FindAllStringSubmatchIndex(text, -1) {
+		var start time.Duration
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+			begin, end := match[2*(i+1)], match[2*(i+1)+1]
+			if begin < 0 {
+				continue
+			}
+			n, err := strconv.Atoi(text[begin:end])
+			if err != nil {
+				continue
+			}
+			start += time.Duration(n) * unit
+		}
+
+		title := strings.Join(strings.Fields(anyLinkRe.ReplaceAllString(text[match[8]:match[9]], "")), " ")
+		if title == "" {
+			continue
+		}
+		chapters = append(chapters, Chapter{Title: title, Start: start, offset: match[0], end: match[1]})
+	}
+
+	for i := 0; i+1 < len(chapters); i++ {
+		next := chapters[i+1].offset
+		if !blankLineRe.MatchString(text[chapters[i].end:next]) {
+			chapters[i].end = next
+		}
+	}
+	return chapters
+}
+
+// chapterAt returns the chapter whose span contains offset, and false if offset is outside
+// every chapter, such as before the chapter list or in a paragraph after it.
+func chapterAt(chapters []Chapter, offset int) (Chapter, bool) {
+	for _, c := range chapters {
+		if c.offset <= offset && offset < c.end {
+			return c, true
+		}
+	}
+	return Chapter{}, false
+}
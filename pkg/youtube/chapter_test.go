@@ -0,0 +1,76 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewURL_Timestamp(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{input: "https://www.youtube.com/watch?v=iDIcydiQOhc", expected: 0},
+		{input: "https://youtu.be/iDIcydiQOhc?t=123", expected: 123 * time.Second},
+		{input: "https://youtu.be/iDIcydiQOhc?t=45s", expected: 45 * time.Second},
+		{input: "https://www.youtube.com/watch?v=iDIcydiQOhc&t=1m30s", expected: 90 * time.Second},
+		{input: "https://www.youtube.com/watch?v=iDIcydiQOhc&list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur&t=1h2m3s", expected: time.Hour + 2*time.Minute + 3*time.Second},
+		{input: "https://youtu.be/iDIcydiQOhc?start=30", expected: 30 * time.Second},
+		{input: "https://www.youtube.com/watch?v=iDIcydiQOhc#t=15", expected: 15 * time.Second},
+		{input: "https://www.youtube.com/watch?v=iDIcydiQOhc&t=soon", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			url, err := NewURL(tt.input)
+			require.NoError(t, err, "NewURL produced an unexpected error")
+			require.Equal(t, "iDIcydiQOhc", url.GetID())
+			require.Equal(t, tt.expected, url.GetTimestamp())
+		})
+	}
+}
+
+func TestParseChapters(t *testing.T) {
+	description := "A video about space.\n\n00:00 Intro\n3:15 - Black holes\n1:02:03 | Outro https://youtu.be/iDIcydiQOhc\nRecorded 12:30 PM"
+	require.Equal(t, []Chapter{
+		{Title: "Intro", Start: 0, offset: 22, end: 34},
+		{Title: "Black holes", Start: 3*time.Minute + 15*time.Second, offset: 34, end: 53},
+		{Title: "Outro", Start: time.Hour + 2*time.Minute + 3*time.Second, offset: 53, end: 97},
+	}, ParseChapters(description))
+}
+
+func TestGetLinksFromText(t *testing.T) {
+	description := "Previously: https://youtu.be/-IfmgyXs7z8\n00:00 Intro\n03:15 Black holes\nSee (https://youtu.be/ztninkgZ0ws?t=90) and https://youtu.be/-IfmgyXs7z8 again.\n10:00 Outro\nNext: https://youtu.be/UwYSWAlAewc\n\n20:00 Bloopers\n\nThanks to https://youtu.be/iDIcydiQOhc"
+	links := GetLinksFromText(description)
+	require.Len(t, links, 5, "expected every occurrence of each link")
+	require.Equal(t, "https://youtu.be/-IfmgyXs7z8", links[0].URL)
+	require.Nil(t, links[0].Chapter, "expected a link before the chapter list to have no chapter")
+	require.Equal(t, "https://youtu.be/ztninkgZ0ws?t=90", links[1].URL, "expected the timestamp to be kept, without the trailing punctuation")
+	require.NotNil(t, links[1].Chapter)
+	require.Equal(t, "Black holes", links[1].Chapter.Title)
+	require.Equal(t, 195*time.Second, links[1].Chapter.Start)
+	require.Equal(t, "https://youtu.be/-IfmgyXs7z8", links[2].URL, "expected a link written again to be kept")
+	require.NotNil(t, links[2].Chapter, "expected the link written again to have the chapter it appears under")
+	require.Equal(t, "Black holes", links[2].Chapter.Title)
+	require.Nil(t, links[3].Chapter, "expected a line after the last chapter of a list to have no chapter")
+	require.Nil(t, links[4].Chapter, "expected a paragraph after the chapter list to have no chapter")
+
+	require.Equal(t, []string{"https://youtu.be/-IfmgyXs7z8", "https://youtu.be/ztninkgZ0ws?t=90", "https://youtu.be/UwYSWAlAewc", "https://youtu.be/iDIcydiQOhc"}, GetUrlsFromText(description), "expected each URL once")
+}
+
+func TestLink_Context(t *testing.T) {
+	description := "Watch our original Quantum Tunneling episode here:\n\nhttps://youtu.be/-IfmgyXs7z8\nPart 1 - https://youtu.be/ztninkgZ0ws\n03:15 Black holes https://youtu.be/UwYSWAlAewc"
+	links := GetLinksFromText(description)
+	require.Len(t, links, 3)
+
+	require.Equal(t, Link{
+		URL:           "https://youtu.be/-IfmgyXs7z8",
+		Offset:        52,
+		Line:          "https://youtu.be/-IfmgyXs7z8",
+		PrecedingLine: "Watch our original Quantum Tunneling episode here:",
+	}, links[0])
+	require.Equal(t, "Watch our original Quantum Tunneling episode here", links[0].Context(), "expected a link on its own line to be described by the preceding line")
+	require.Equal(t, "Part 1", links[1].Context())
+	require.Equal(t, "Black holes", links[2].Context(), "expected the chapter timestamp to be left out")
+}
@@ -2,47 +2,136 @@ package youtube
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"html"
+	"math/rand"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/inconshreveable/log15"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
+const (
+	// MaxIDsPerRequest is the maximum number of video IDs accepted by a single videos.list call
+	MaxIDsPerRequest = 50
+)
+
 var (
+	videoParts = []string{"id", "snippet", "contentDetails", "player", "statistics"}
+
 	maxResults = flag.Int64("max-results", 25, "Max Youtube Results")
 )
 
+// Client is the Source backed by the youtube Data API.
 type Client interface {
-	GetVideoByTitle(title string) (Video, error)
-	GetVideoByURL(rawURL string) (Video, error)
-	GetVideoByID(id string) (Video, error)
+	Source
+}
+
+// MissingVideosError is returned by GetVideosByIDs when some of the requested IDs
+// did not match any video. The videos that were found are still returned alongside it.
+type MissingVideosError struct {
+	IDs []string
+	// Errors contains the cause for any of the IDs known to be more specific than not being
+	// found, such as ErrPrivateVideo
+	Errors map[string]error
+}
+
+func (e *MissingVideosError) Error() string {
+	return fmt.Sprintf("no videos found with ids %s", strings.Join(e.IDs, ","))
+}
+
+// Unwrap allows errors.Is(err, ErrVideoNotFound) to match a *MissingVideosError.
+func (e *MissingVideosError) Unwrap() error {
+	return ErrVideoNotFound
 }
 
 type ytClient struct {
-	apiKey  string
-	service *youtube.Service
-	log     log15.Logger
+	apiKey    string
+	service   *youtube.Service
+	log       log15.Logger
+	quota     *QuotaTracker
+	retry     RetryPolicy
+	rand      *rand.Rand
+	sleep     func(time.Duration)
+	transport http.RoundTripper
+}
+
+// ClientOption configures optional behavior of the Client created by NewClient.
+type ClientOption func(c *ytClient)
+
+// WithQuotaTracker records the quota units spent by the client in q, and stops the client
+// from making calls once the budget of q is spent.
+func WithQuotaTracker(q *QuotaTracker) ClientOption {
+	return func(c *ytClient) {
+		c.quota = q
+	}
 }
 
-func NewClient(apiKey string, log log15.Logger) (Client, error) {
-	if apiKey == "" {
+// WithTransport makes every API call using rt, such as to record or replay the responses
+// of the API. The API key is added to each request before it is passed to rt.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *ytClient) {
+		c.transport = rt
+	}
+}
+
+// NewClient creates a Client calling the Data API with apiKey. The key may only be empty
+// along with WithTransport, such as when every response is replayed from a recording.
+func NewClient(apiKey string, log log15.Logger, opts ...ClientOption) (Client, error) {
+	c := &ytClient{
+		apiKey: apiKey,
+		log:    log,
+		quota:  NewQuotaTracker(0),
+		retry:  DefaultRetryPolicy,
+		rand:   newRand(time.Now().UnixNano()),
+		sleep:  time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if apiKey == "" && c.transport == nil {
 		return &ytClient{}, fmt.Errorf("provided api key is empty")
 	}
 
-	service, err := youtube.NewService(context.Background(), option.WithAPIKey(apiKey))
+	// option.WithHTTPClient takes precedence over option.WithAPIKey, so the key
+	// must be added to the requests by the transport itself
+	clientOpt := option.WithAPIKey(apiKey)
+	if c.transport != nil {
+		clientOpt = option.WithHTTPClient(&http.Client{Transport: &apiKeyTransport{apiKey: apiKey, next: c.transport}})
+	}
+
+	service, err := youtube.NewService(context.Background(), clientOpt)
 	if err != nil {
 		return &ytClient{}, fmt.Errorf("unable to create youtube service: %s", err)
 	}
+	c.service = service
+	return c, nil
+}
+
+// apiKeyTransport adds the API key to every request before passing it to next.
+type apiKeyTransport struct {
+	apiKey string
+	next   http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.apiKey == "" {
+		return t.next.RoundTrip(req)
+	}
 
-	return &ytClient{
-		apiKey:  apiKey,
-		service: service,
-		log:     log,
-	}, nil
+	// A RoundTripper must not modify the request it is given
+	keyed := req.Clone(req.Context())
+	query := keyed.URL.Query()
+	query.Set("key", t.apiKey)
+	keyed.URL.RawQuery = query.Encode()
+	return t.next.RoundTrip(keyed)
 }
 
 func (c *ytClient) GetVideoByTitle(title string) (Video, error) {
@@ -51,9 +140,14 @@ func (c *ytClient) GetVideoByTitle(title string) (Video, error) {
 	// Perform a search, retrieving the 'id' and 'snippet' of the results,
 	// limiting the number of results to maxResults
 	searchListCall := c.service.Search.List([]string{"id", "snippet"}).Q(*query).MaxResults(*maxResults)
-	response, err := searchListCall.Do()
+	var response *youtube.SearchListResponse
+	err := c.do(CallSearchList, CostSearchList, func() error {
+		var err error
+		response, err = searchListCall.Do()
+		return err
+	})
 	if err != nil {
-		return &video{}, fmt.Errorf("unable to do call: %s", err)
+		return &video{}, fmt.Errorf("unable to do call: %w", err)
 	}
 
 	// The Title's in the results are HTML-encoded, therefore to get an
@@ -89,13 +183,17 @@ func (c *ytClient) GetVideoByTitle(title string) (Video, error) {
 		}
 	}
 
+	if !foundMatch {
+		return &video{}, fmt.Errorf("%w: no search results with title %s", ErrVideoNotFound, title)
+	}
+
 	return c.GetVideoByID(matchingVideoID)
 }
 
 func (c *ytClient) GetVideoByURL(rawURL string) (Video, error) {
 	url, err := NewURL(rawURL)
 	if err != nil {
-		return &video{}, fmt.Errorf("unable to create new URL from raw url %s: %s", rawURL, err)
+		return &video{}, fmt.Errorf("unable to create new URL from raw url %s: %w", rawURL, err)
 	}
 	return c.getVideo(url)
 }
@@ -103,28 +201,270 @@ func (c *ytClient) GetVideoByURL(rawURL string) (Video, error) {
 func (c *ytClient) GetVideoByID(id string) (Video, error) {
 	url, err := NewURL(id)
 	if err != nil {
-		return &video{}, fmt.Errorf("unable to create new URL from video id %s: %s", id, err)
+		return &video{}, fmt.Errorf("unable to create new URL from video id %s: %w", id, err)
 	}
 	return c.getVideo(url)
 }
 
-func (c *ytClient) getVideo(url Url) (Video, error) {
-	// Query for all the relevant information
-	videoListCall := c.service.Videos.List([]string{"id", "snippet", "contentDetails", "player"})
+// GetVideosByIDs returns the videos matching the given ids, keyed by video ID. The ids
+// are requested in chunks of MaxIDsPerRequest, so that each chunk costs a single call.
+// If any of the ids do not match a video, the found videos are returned along with a
+// *MissingVideosError listing the missing ids.
+func (c *ytClient) GetVideosByIDs(ids []string) (map[string]Video, error) {
+	videos := map[string]Video{}
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	for start := 0; start < len(unique); start += MaxIDsPerRequest {
+		end := start + MaxIDsPerRequest
+		if end > len(unique) {
+			end = len(unique)
+		}
 
+		items, err := c.listVideos(unique[start:end])
+		if err != nil {
+			return videos, err
+		}
+		for _, item := range items {
+			videos[item.Id] = newVideo(item)
+		}
+	}
+
+	missing := []string{}
+	for _, id := range unique {
+		if _, ok := videos[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return videos, &MissingVideosError{IDs: missing}
+	}
+
+	return videos, nil
+}
+
+func (c *ytClient) getVideo(url Url) (Video, error) {
 	// Get a video by the video ID
-	videoListCall.Id(url.GetID())
-	response, err := videoListCall.Do()
+	items, err := c.listVideos([]string{url.GetID()})
 	if err != nil {
-		return &video{}, fmt.Errorf("unable to perform video list by id: %s", err)
+		return &video{}, err
 	}
 
 	// We expect this ID to be unique, meaning only 0 or 1 result should be returned
+	if len(items) < 1 {
+		return &video{}, fmt.Errorf("%w: no videos found with origin=%s id=%s", ErrVideoNotFound, url.GetOrigin(), url.GetID())
+	} else if len(items) > 1 {
+		return &video{}, fmt.Errorf("too many videos found (%d) with origin=%s id=%s", len(items), url.GetOrigin(), url.GetID())
+	}
+
+	return newVideo(items[0]), nil
+}
+
+// listVideos performs a single videos.list call for the given ids, querying for all the relevant information.
+func (c *ytClient) listVideos(ids []string) ([]*youtube.Video, error) {
+	videoListCall := c.service.Videos.List(videoParts).Id(ids...)
+	var response *youtube.VideoListResponse
+	err := c.do(CallVideosList, CostVideosList, func() error {
+		var err error
+		response, err = videoListCall.Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform video list by id: %w", err)
+	}
+	return response.Items, nil
+}
+
+// GetPlaylistByID returns the playlist with the given id, along with the IDs of its first
+// maxItems videos, where 0 is unlimited. The items of the playlist are paged through,
+// MaxIDsPerRequest at a time, until maxItems videos are found.
+func (c *ytClient) GetPlaylistByID(id string, maxItems int) (Playlist, error) {
+	playlistsListCall := c.service.Playlists.List([]string{"id", "snippet", "contentDetails"}).Id(id)
+	var response *youtube.PlaylistListResponse
+	err := c.do(CallPlaylistsList, CostPlaylistsList, func() error {
+		var err error
+		response, err = playlistsListCall.Do()
+		return err
+	})
+	if err != nil {
+		return &playlist{}, fmt.Errorf("unable to perform playlist list by id: %w", err)
+	}
 	if len(response.Items) < 1 {
-		return &video{}, fmt.Errorf("no videos found with origin=%s id=%s", url.GetOrigin(), url.GetID())
-	} else if len(response.Items) > 1 {
-		return &video{}, fmt.Errorf("too many videos found (%d) with origin=%s id=%s", len(response.Items), url.GetOrigin(), url.GetID())
+		return &playlist{}, fmt.Errorf("%w: no playlists found with id=%s", ErrPlaylistNotFound, id)
+	}
+	snippet := response.Items[0].Snippet
+	if snippet == nil {
+		snippet = &youtube.PlaylistSnippet{}
+	}
+
+	videoIDs := []string{}
+	err = c.listPlaylistItems(id, func(item *youtube.PlaylistItem) bool {
+		if item.ContentDetails != nil && item.ContentDetails.VideoId != "" && !contains(videoIDs, item.ContentDetails.VideoId) {
+			videoIDs = append(videoIDs, item.ContentDetails.VideoId)
+		}
+		return maxItems <= 0 || len(videoIDs) < maxItems
+	})
+	if err != nil {
+		return &playlist{}, err
+	}
+
+	pl := &playlist{
+		id:           id,
+		title:        strings.TrimSpace(snippet.Title),
+		channelID:    snippet.ChannelId,
+		channelTitle: snippet.ChannelTitle,
+		videoIDs:     videoIDs,
+		itemCount:    len(videoIDs),
+	}
+	if details := response.Items[0].ContentDetails; details != nil && int(details.ItemCount) > pl.itemCount {
+		pl.itemCount = int(details.ItemCount)
+	}
+	return pl, nil
+}
+
+// listPlaylistItems pages through the items of the playlist with the given id, MaxIDsPerRequest
+// at a time, calling visit with each item until it returns false.
+func (c *ytClient) listPlaylistItems(id string, visit func(item *youtube.PlaylistItem) bool) error {
+	pageToken := ""
+	for {
+		itemsListCall := c.service.PlaylistItems.List([]string{"contentDetails"}).PlaylistId(id).MaxResults(MaxIDsPerRequest)
+		if pageToken != "" {
+			itemsListCall = itemsListCall.PageToken(pageToken)
+		}
+		var response *youtube.PlaylistItemListResponse
+		err := c.do(CallPlaylistItemsList, CostPlaylistItemsList, func() error {
+			var err error
+			response, err = itemsListCall.Do()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to perform playlist item list by playlist id %s: %w", id, err)
+		}
+
+		for _, item := range response.Items {
+			if !visit(item) {
+				return nil
+			}
+		}
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			return nil
+		}
+	}
+}
+
+// GetChannel returns the channel identified by ref, looking it up by handle if ref has no ID.
+func (c *ytClient) GetChannel(ref ChannelRef) (Channel, error) {
+	channelsListCall := c.service.Channels.List([]string{"id", "snippet", "contentDetails"})
+	opts := []googleapi.CallOption{}
+	if ref.ID != "" {
+		channelsListCall = channelsListCall.Id(ref.ID)
+	} else {
+		// The version of the client library in use predates the forHandle parameter
+		opts = append(opts, googleapi.QueryParameter("forHandle", ref.Handle))
 	}
 
-	return newVideo(response.Items[0]), nil
+	var response *youtube.ChannelListResponse
+	err := c.do(CallChannelsList, CostChannelsList, func() error {
+		var err error
+		response, err = channelsListCall.Do(opts...)
+		return err
+	})
+	if err != nil {
+		return &channel{}, fmt.Errorf("unable to perform channel list by %s: %w", ref, err)
+	}
+	if len(response.Items) < 1 {
+		return &channel{}, fmt.Errorf("%w: no channels found for %s", ErrChannelNotFound, ref)
+	}
+
+	item := response.Items[0]
+	title := ""
+	if item.Snippet != nil {
+		title = item.Snippet.Title
+	}
+	uploads := ""
+	if item.ContentDetails != nil && item.ContentDetails.RelatedPlaylists != nil {
+		uploads = item.ContentDetails.RelatedPlaylists.Uploads
+	}
+	return NewChannel(item.Id, title, uploads), nil
+}
+
+// GetUploads pages through the uploads playlist of ch, which is ordered from the most recent
+// upload, so that paging stops as soon as filter is satisfied or the uploads are too old.
+func (c *ytClient) GetUploads(ch Channel, filter UploadsFilter) ([]string, error) {
+	ids := []string{}
+	err := c.listPlaylistItems(ch.GetUploadsPlaylistID(), func(item *youtube.PlaylistItem) bool {
+		if item.ContentDetails == nil || item.ContentDetails.VideoId == "" {
+			return true
+		}
+		// Uploads without a publish time, such as private videos, can't be watched anyway
+		publishedAt, err := time.Parse(time.RFC3339, item.ContentDetails.VideoPublishedAt)
+		if err != nil {
+			c.log.Debug("Skipping upload without a publish time", "id", item.ContentDetails.VideoId, "channel", ch.GetID())
+			return true
+		}
+		if !filter.PublishedAfter.IsZero() && publishedAt.Before(filter.PublishedAfter) {
+			return false
+		}
+		if filter.includes(publishedAt) && !contains(ids, item.ContentDetails.VideoId) {
+			ids = append(ids, item.ContentDetails.VideoId)
+		}
+		return !filter.full(len(ids))
+	})
+	return ids, err
+}
+
+// GetComments pages through the top-level comments on vid, MaxCommentsPerPage at a time, until
+// filter.MaxPages pages have been fetched. Comments are ordered by relevance, which places a
+// pinned comment first, and are fetched as plain text so that links aren't wrapped in HTML.
+// A video with its comments disabled has no comments.
+func (c *ytClient) GetComments(vid Video, filter CommentFilter) ([]Comment, error) {
+	comments := []Comment{}
+	pageToken := ""
+	for page := 1; filter.MaxPages == 0 || page <= filter.MaxPages; page++ {
+		threadsListCall := c.service.CommentThreads.List([]string{"snippet"}).VideoId(vid.GetID()).Order("relevance").TextFormat("plainText").MaxResults(MaxCommentsPerPage)
+		if pageToken != "" {
+			threadsListCall = threadsListCall.PageToken(pageToken)
+		}
+		var response *youtube.CommentThreadListResponse
+		err := c.do(CallCommentThreadsList, CostCommentThreadsList, func() error {
+			var err error
+			response, err = threadsListCall.Do()
+			return err
+		})
+		if errors.Is(err, ErrCommentsDisabled) {
+			c.log.Debug("Comments disabled", "video", vid.GetID())
+			return comments, nil
+		}
+		if err != nil {
+			return comments, fmt.Errorf("unable to perform comment thread list by video id %s: %w", vid.GetID(), err)
+		}
+
+		for _, thread := range response.Items {
+			if thread.Snippet == nil || thread.Snippet.TopLevelComment == nil || thread.Snippet.TopLevelComment.Snippet == nil {
+				continue
+			}
+			snippet := thread.Snippet.TopLevelComment.Snippet
+			author := ""
+			if snippet.AuthorChannelId != nil {
+				author = snippet.AuthorChannelId.Value
+			}
+			if filter.includes(vid, author) {
+				comments = append(comments, NewComment(thread.Snippet.TopLevelComment.Id, vid.GetID(), author, snippet.TextDisplay))
+			}
+		}
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	return comments, nil
 }
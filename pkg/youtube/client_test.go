@@ -0,0 +1,131 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TrevorEdris/youtube-dependency-graph/pkg/recorder"
+)
+
+// roundTripFunc is an http.RoundTripper calling itself, standing in for the Data API.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+const videosListResponse = `{"items":[{"kind":"youtube#video","id":"iDIcydiQOhc","snippet":{"title":"New Results in Quantum Tunneling vs. The Speed of Light","channelTitle":"PBS Space Time"}}]}`
+
+func TestClient_RecordReplay(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+	dir := t.TempDir()
+
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		require.Equal(t, "s3cr3t", req.URL.Query().Get("key"), "expected the API key to be added to the request")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(videosListResponse)),
+			Request:    req,
+		}, nil
+	})
+	rec, err := recorder.New(recorder.ModeRecord, dir, recorder.WithTransport(api), recorder.WithSecrets("s3cr3t"))
+	require.NoError(t, err, "recorder.New produced an unexpected error")
+
+	c, err := NewClient("s3cr3t", log, WithTransport(rec))
+	require.NoError(t, err, "NewClient produced an unexpected error")
+	vid, err := c.GetVideoByID("iDIcydiQOhc")
+	require.NoError(t, err, "GetVideoByID produced an unexpected error while recording")
+	require.Equal(t, "PBS Space Time", vid.GetChannelTitle())
+
+	// Replaying requires no API key, and never reaches the API
+	rec, err = recorder.New(recorder.ModeReplay, dir)
+	require.NoError(t, err, "recorder.New produced an unexpected error")
+	c, err = NewClient("", log, WithTransport(rec))
+	require.NoError(t, err, "NewClient produced an unexpected error")
+	vid, err = c.GetVideoByID("iDIcydiQOhc")
+	require.NoError(t, err, "GetVideoByID produced an unexpected error while replaying")
+	require.Equal(t, "New Results in Quantum Tunneling vs. The Speed of Light", vid.GetTitle())
+
+	_, err = NewClient("", log)
+	require.Error(t, err, "expected an empty API key to be rejected without a transport")
+}
+
+func TestClient_GetVideosByIDs(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	missing := map[string]bool{"video000007": true, "video000093": true}
+	var mu sync.Mutex
+	batches := [][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/youtube/v3/videos", r.URL.Path, "expected only videos.list to be called")
+		ids := []string{}
+		for _, param := range r.URL.Query()["id"] {
+			ids = append(ids, strings.Split(param, ",")...)
+		}
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+
+		items := []string{}
+		for _, id := range ids {
+			if !missing[id] {
+				items = append(items, fmt.Sprintf(`{"kind":"youtube#video","id":%q,"snippet":{"title":"Title of %s"}}`, id, id))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := neturl.Parse(server.URL)
+	require.NoError(t, err, "unable to parse the test server URL")
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = serverURL.Scheme
+		req.URL.Host = serverURL.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	quota := NewQuotaTracker(0)
+	c, err := NewClient("s3cr3t", log, WithTransport(api), WithQuotaTracker(quota))
+	require.NoError(t, err, "NewClient produced an unexpected error")
+
+	// 120 unique IDs, each of the first ten requested twice
+	ids := []string{}
+	for i := 0; i < 120; i++ {
+		ids = append(ids, fmt.Sprintf("video%06d", i))
+	}
+	ids = append(ids, ids[:10]...)
+
+	videos, err := c.GetVideosByIDs(ids)
+	var missingErr *MissingVideosError
+	require.True(t, errors.As(err, &missingErr), "expected a *MissingVideosError, got %v", err)
+	require.Equal(t, []string{"video000007", "video000093"}, missingErr.IDs, "expected the IDs left out by the API, in the order requested")
+	require.ErrorIs(t, err, ErrVideoNotFound)
+	require.Len(t, videos, 118, "expected the videos found to be returned alongside the error")
+	require.Equal(t, "Title of video000042", videos["video000042"].GetTitle())
+
+	require.Len(t, batches, 3, "expected the unique IDs to be chunked into calls of at most %d", MaxIDsPerRequest)
+	requested := map[string]bool{}
+	for i, batch := range batches {
+		require.LessOrEqual(t, len(batch), MaxIDsPerRequest, "batch %d holds too many IDs", i)
+		for _, id := range batch {
+			require.False(t, requested[id], "expected %s to be requested once", id)
+			requested[id] = true
+		}
+	}
+	require.Len(t, requested, 120)
+	require.Equal(t, []CallUsage{{Call: CallVideosList, Calls: 3, Units: 3 * CostVideosList}}, quota.Summary().Calls)
+}
@@ -0,0 +1,103 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxCommentsPerPage is the maximum number of comment threads returned by a single commentThreads.list call
+	MaxCommentsPerPage = 100
+)
+
+// Comment is a top-level comment on a video.
+type Comment interface {
+	GetID() string
+	GetVideoID() string
+	// GetAuthorChannelID returns the ID of the channel that wrote the comment
+	GetAuthorChannelID() string
+	GetText() string
+	GetUrlsFromText() []string
+	GetLinksFromText() []Link
+	GetPlaylistUrlsFromText() []string
+}
+
+// CommentFilter limits the comments returned by a CommentSource.
+type CommentFilter struct {
+	// AllAuthors includes comments from every author, instead of only those written by the
+	// channel that uploaded the video, such as a pinned comment
+	AllAuthors bool
+	// MaxPages is the maximum number of pages of MaxCommentsPerPage comment threads fetched
+	// per video, where 0 is unlimited
+	MaxPages int
+}
+
+// includes returns true if a comment on vid written by authorChannelID passes the filter.
+func (f CommentFilter) includes(vid Video, authorChannelID string) bool {
+	return f.AllAuthors || (authorChannelID != "" && authorChannelID == vid.GetChannelID())
+}
+
+// CommentSource is implemented by any Source that is also able to look up the comments on a video.
+type CommentSource interface {
+	// GetComments returns the top-level comments on vid that pass filter, the most relevant first
+	GetComments(vid Video, filter CommentFilter) ([]Comment, error)
+}
+
+// GetComments looks up the comments on vid using src, returning ErrUnsupported if src is unable
+// to look up comments.
+func GetComments(src Source, vid Video, filter CommentFilter) ([]Comment, error) {
+	cs, ok := src.(CommentSource)
+	if !ok {
+		return []Comment{}, fmt.Errorf("%w: unable to get comments on video %s", ErrUnsupported, vid.GetID())
+	}
+	return cs.GetComments(vid, filter)
+}
+
+type comment struct {
+	id              string
+	videoID         string
+	authorChannelID string
+	text            string
+}
+
+// NewComment creates the Comment with the given id, written on the video with the given videoID.
+func NewComment(id, videoID, authorChannelID, text string) Comment {
+	return &comment{
+		id:              strings.TrimSpace(id),
+		videoID:         strings.TrimSpace(videoID),
+		authorChannelID: strings.TrimSpace(authorChannelID),
+		text:            text,
+	}
+}
+
+func (c *comment) GetID() string {
+	return c.id
+}
+
+func (c *comment) GetVideoID() string {
+	return c.videoID
+}
+
+func (c *comment) GetAuthorChannelID() string {
+	return c.authorChannelID
+}
+
+func (c *comment) GetText() string {
+	return c.text
+}
+
+// GetUrlsFromText returns the unique links to videos in the comment.
+func (c *comment) GetUrlsFromText() []string {
+	return GetUrlsFromText(c.text)
+}
+
+// GetLinksFromText returns every occurrence of a link to a video in the comment, along with
+// the chapter it appears under.
+func (c *comment) GetLinksFromText() []Link {
+	return GetLinksFromText(c.text)
+}
+
+// GetPlaylistUrlsFromText returns the unique links to playlists in the comment.
+func (c *comment) GetPlaylistUrlsFromText() []string {
+	return GetPlaylistUrlsFromText(c.text)
+}
@@ -0,0 +1,78 @@
+package youtube
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+	ytapi "google.golang.org/api/youtube/v3"
+)
+
+func TestClient_GetCommentsCapsPages(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	thread := func(id, author, text string) string {
+		return `{"snippet":{"topLevelComment":{"id":"` + id + `","snippet":{"authorChannelId":{"value":"` + author + `"},"textDisplay":"` + text + `"}}}}`
+	}
+	pages := map[string]string{
+		"":      `{"nextPageToken":"page2","items":[` + thread("c1", "UCtest", "Previous episode: https://youtu.be/aaaaaaaaaaa") + `,` + thread("c2", "UCviewer", "https://youtu.be/bbbbbbbbbbb") + `]}`,
+		"page2": `{"nextPageToken":"page3","items":[` + thread("c3", "UCtest", "Playlist: https://www.youtube.com/playlist?list=PLtest0000000") + `]}`,
+	}
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		require.Equal(t, "vvvvvvvvvvv", req.URL.Query().Get("videoId"))
+		body, ok := pages[req.URL.Query().Get("pageToken")]
+		require.True(t, ok, "expected no more than MaxPages pages to be requested")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+
+	quota := NewQuotaTracker(0)
+	c, err := NewClient("key", log, WithTransport(api), WithQuotaTracker(quota))
+	require.NoError(t, err, "NewClient produced an unexpected error")
+	vid := NewVideo(&ytapi.Video{Id: "vvvvvvvvvvv", Snippet: &ytapi.VideoSnippet{ChannelId: "UCtest"}})
+
+	comments, err := GetComments(c, vid, CommentFilter{MaxPages: 2})
+	require.NoError(t, err, "GetComments produced an unexpected error")
+	require.Len(t, comments, 2, "expected only the comments of the video's channel")
+	require.Equal(t, []string{"https://youtu.be/aaaaaaaaaaa"}, comments[0].GetUrlsFromText())
+	require.Equal(t, []string{"https://www.youtube.com/playlist?list=PLtest0000000"}, comments[1].GetPlaylistUrlsFromText())
+	require.Equal(t, 2*CostCommentThreadsList, quota.Summary().Units)
+
+	comments, err = GetComments(c, vid, CommentFilter{AllAuthors: true, MaxPages: 1})
+	require.NoError(t, err, "GetComments produced an unexpected error")
+	require.Len(t, comments, 2, "expected the comments of every author on the first page")
+	require.Equal(t, "UCviewer", comments[1].GetAuthorChannelID())
+
+	_, err = GetComments(newMapSource(ErrVideoNotFound), vid, CommentFilter{})
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestClient_GetCommentsDisabled(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"error":{"code":403,"message":"comments disabled","errors":[{"reason":"commentsDisabled"}]}}`
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+
+	c, err := NewClient("key", log, WithTransport(api))
+	require.NoError(t, err, "NewClient produced an unexpected error")
+
+	comments, err := GetComments(c, NewVideo(&ytapi.Video{Id: "vvvvvvvvvvv"}), CommentFilter{MaxPages: 1})
+	require.NoError(t, err, "expected a video with its comments disabled to have no comments")
+	require.Empty(t, comments)
+}
@@ -0,0 +1,144 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Typed errors returned by the client. Errors returned by the client wrap one of these
+// when the cause is known, so callers can check for them with errors.Is.
+var (
+	ErrVideoNotFound    = errors.New("video not found")
+	ErrVideoDeleted     = errors.New("video deleted")
+	ErrPrivateVideo     = errors.New("video is private")
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrChannelNotFound  = errors.New("channel not found")
+	ErrCommentsDisabled = errors.New("comments disabled")
+	ErrInvalidURL       = errors.New("invalid video url")
+	ErrQuotaExceeded    = errors.New("api quota exceeded")
+	ErrRateLimited      = errors.New("api rate limited")
+	ErrUnavailable      = errors.New("api unavailable")
+	ErrInvalidRequest   = errors.New("invalid api request")
+	ErrForbidden        = errors.New("api request forbidden")
+)
+
+// Reasons reported by the Data API in googleapi.ErrorItem.
+// https://developers.google.com/youtube/v3/docs/errors
+const (
+	reasonQuotaExceeded         = "quotaExceeded"
+	reasonDailyLimitExceeded    = "dailyLimitExceeded"
+	reasonRateLimitExceeded     = "rateLimitExceeded"
+	reasonUserRateLimitExceeded = "userRateLimitExceeded"
+	reasonVideoNotFound         = "videoNotFound"
+	reasonPlaylistNotFound      = "playlistNotFound"
+	reasonChannelNotFound       = "channelNotFound"
+	reasonCommentsDisabled      = "commentsDisabled"
+	reasonForbidden             = "forbidden"
+)
+
+// IsRetryable returns true if err is caused by a failure that may succeed if the call is made again.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable)
+}
+
+// classifyError wraps err returned by a call to the Data API with the typed error matching its cause.
+func classifyError(call string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return &apiError{kind: errorFromAPI(call, apiErr), call: call, err: err}
+	}
+
+	// Failures to reach the API at all, such as timeouts or connection resets
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%w: %s: %s", ErrUnavailable, call, err)
+	}
+
+	return fmt.Errorf("%s: %s", call, err)
+}
+
+// apiError is an error returned by a call to the Data API, classified by kind. It matches kind
+// with errors.Is, while the *googleapi.Error it wraps is still found by errors.As.
+type apiError struct {
+	kind error
+	call string
+	err  error
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.kind, e.call, e.err)
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+func (e *apiError) Is(target error) bool {
+	return target == e.kind
+}
+
+// errorFromAPI returns the typed error matching the status code and reasons of apiErr, returned
+// by the given call.
+func errorFromAPI(call string, apiErr *googleapi.Error) error {
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case reasonQuotaExceeded, reasonDailyLimitExceeded:
+			return ErrQuotaExceeded
+		case reasonRateLimitExceeded, reasonUserRateLimitExceeded:
+			return ErrRateLimited
+		case reasonVideoNotFound:
+			return ErrVideoNotFound
+		case reasonPlaylistNotFound:
+			return ErrPlaylistNotFound
+		case reasonChannelNotFound:
+			return ErrChannelNotFound
+		case reasonCommentsDisabled:
+			return ErrCommentsDisabled
+		case reasonForbidden:
+			// Only a forbidden video lookup means the video is private, rather than, say, its comments
+			if call == CallVideosList {
+				return ErrPrivateVideo
+			}
+			return ErrForbidden
+		}
+	}
+
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case apiErr.Code == http.StatusNotFound:
+		return notFoundError(call)
+	case apiErr.Code == http.StatusForbidden:
+		return ErrForbidden
+	case apiErr.Code >= http.StatusInternalServerError:
+		return ErrUnavailable
+	}
+	return ErrInvalidRequest
+}
+
+// notFoundError returns the typed error for a 404 returned by the given call, naming the kind of
+// object the call looks up.
+func notFoundError(call string) error {
+	switch call {
+	case CallVideosList, CallCommentThreadsList:
+		return ErrVideoNotFound
+	case CallPlaylistsList, CallPlaylistItemsList:
+		return ErrPlaylistNotFound
+	case CallChannelsList:
+		return ErrChannelNotFound
+	}
+	return ErrInvalidRequest
+}
+
+// IsQuotaError returns true if err is caused by the API quota, or the quota budget of the client, being spent.
+func IsQuotaError(err error) bool {
+	return errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrQuotaBudgetExceeded)
+}
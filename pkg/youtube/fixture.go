@@ -0,0 +1,429 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ytapi "google.golang.org/api/youtube/v3"
+)
+
+// Errors that a fixture can be configured to fail with, keyed by the value of its error field.
+var fixtureErrors = map[string]error{
+	"not_found":      ErrVideoNotFound,
+	"private":        ErrPrivateVideo,
+	"deleted":        ErrVideoDeleted,
+	"invalid_url":    ErrInvalidURL,
+	"quota_exceeded": ErrQuotaExceeded,
+	"rate_limited":   ErrRateLimited,
+	"unavailable":    ErrUnavailable,
+}
+
+// fixture is the JSON representation of a single video, or of the error returned when it is requested.
+/*
+{
+    "id": "iDIcydiQOhc",
+    "title": "New Results in Quantum Tunneling vs. The Speed of Light",
+    "description": "Watch our original Quantum Tunneling episode here:\nhttps://youtu.be/-IfmgyXs7z8",
+    "channelId": "UC7_gcs09iThXybpVgjHZ_7g",
+    "channelTitle": "PBS Space Time",
+    "publishedAt": "2021-10-13T20:56:02Z",
+    "duration": "PT15M27S",
+    "viewCount": 462134,
+    "likeCount": 19231,
+    "comments": [
+        {"id": "UgzVs3Aq", "authorChannelId": "UC7_gcs09iThXybpVgjHZ_7g", "text": "Previous episode: https://youtu.be/-IfmgyXs7z8"}
+    ]
+}
+
+{
+    "id": "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur",
+    "type": "playlist",
+    "title": "Space Time Season 1",
+    "channelId": "UC7_gcs09iThXybpVgjHZ_7g",
+    "channelTitle": "PBS Space Time",
+    "videoIds": ["iDIcydiQOhc", "-IfmgyXs7z8"]
+}
+
+{
+    "id": "UC7_gcs09iThXybpVgjHZ_7g",
+    "type": "channel",
+    "title": "PBS Space Time",
+    "handle": "@pbsspacetime"
+}
+
+{
+    "id": "YWxub2XhmXM",
+    "error": "private"
+}
+*/
+type fixture struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	ChannelID    string `json:"channelId"`
+	ChannelTitle string `json:"channelTitle"`
+	PublishedAt  string `json:"publishedAt"`
+	Duration     string `json:"duration"`
+	ViewCount    uint64 `json:"viewCount"`
+	LikeCount    uint64 `json:"likeCount"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	// Type is either video, the default, playlist or channel
+	Type string `json:"type,omitempty"`
+	// Handle is the @handle of a channel
+	Handle string `json:"handle,omitempty"`
+	// VideoIDs are the IDs of the videos in a playlist, in playlist order
+	VideoIDs []string `json:"videoIds,omitempty"`
+	// Comments are the top-level comments on a video, the most relevant first
+	Comments []commentFixture `json:"comments,omitempty"`
+	// Error is the name of the error returned when the video is requested, such as private
+	Error string `json:"error,omitempty"`
+}
+
+// commentFixture is the JSON representation of a single top-level comment on a video fixture.
+type commentFixture struct {
+	ID              string `json:"id"`
+	AuthorChannelID string `json:"authorChannelId"`
+	Text            string `json:"text"`
+}
+
+// fixtureClient is a Client serving videos loaded from a directory of JSON fixtures, allowing
+// crawls to be run deterministically and offline. Each lookup spends the quota units of the Data
+// API calls it stands for, so that quota budgets can be tested offline too.
+type fixtureClient struct {
+	quota     *QuotaTracker
+	videos    map[string]Video
+	playlists map[string]Playlist
+	channels  map[string]Channel
+	comments  map[string][]Comment
+	// handles contains the ID of each channel, keyed by lowercase handle
+	handles map[string]string
+	errors  map[string]error
+	// titles contains the IDs of the fixtures in the order they were loaded, for title searches
+	titles []string
+}
+
+// FixtureOption configures optional behavior of the Client created by NewFixtureClient.
+type FixtureOption func(c *fixtureClient)
+
+// WithFixtureQuotaTracker records the quota units the fixture client would have spent calling
+// the Data API in q, and stops the client from serving fixtures once the budget of q is spent.
+func WithFixtureQuotaTracker(q *QuotaTracker) FixtureOption {
+	return func(c *fixtureClient) {
+		c.quota = q
+	}
+}
+
+// NewFixtureClient creates a Client serving the fixtures in every *.json file of dir. Each
+// file holds either a single fixture or an array of fixtures. Videos without a fixture are
+// not found.
+func NewFixtureClient(dir string, opts ...FixtureOption) (Client, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return &fixtureClient{}, fmt.Errorf("unable to list fixtures in %s: %s", dir, err)
+	}
+	if len(paths) == 0 {
+		return &fixtureClient{}, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	c := &fixtureClient{
+		quota:     NewQuotaTracker(0),
+		videos:    map[string]Video{},
+		playlists: map[string]Playlist{},
+		channels:  map[string]Channel{},
+		comments:  map[string][]Comment{},
+		handles:   map[string]string{},
+		errors:    map[string]error{},
+		titles:    []string{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for _, path := range paths {
+		fixtures, err := readFixtures(path)
+		if err != nil {
+			return &fixtureClient{}, err
+		}
+		for _, f := range fixtures {
+			err = c.add(f)
+			if err != nil {
+				return &fixtureClient{}, fmt.Errorf("invalid fixture in %s: %s", path, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+func readFixtures(path string) ([]fixture, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read fixture %s: %s", path, err)
+	}
+
+	fixtures := []fixture{}
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "[") {
+		err = json.Unmarshal(b, &fixtures)
+	} else {
+		f := fixture{}
+		err = json.Unmarshal(b, &f)
+		fixtures = append(fixtures, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal fixture %s: %s", path, err)
+	}
+	return fixtures, nil
+}
+
+func (c *fixtureClient) add(f fixture) error {
+	if strings.TrimSpace(f.ID) == "" {
+		return fmt.Errorf("fixture id cannot be empty")
+	}
+
+	if f.Error != "" {
+		err, ok := fixtureErrors[f.Error]
+		if !ok {
+			return fmt.Errorf("unsupported error %s for fixture %s", f.Error, f.ID)
+		}
+		c.errors[f.ID] = fmt.Errorf("%w: fixture %s", err, f.ID)
+		return nil
+	}
+
+	switch f.Type {
+	case "", "video":
+	case "playlist":
+		c.playlists[f.ID] = NewPlaylist(f.ID, f.Title, f.ChannelID, f.ChannelTitle, f.VideoIDs)
+		return nil
+	case "channel":
+		c.channels[f.ID] = NewChannel(f.ID, f.Title, "")
+		if f.Handle != "" {
+			c.handles[strings.ToLower(f.Handle)] = f.ID
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %s for fixture %s", f.Type, f.ID)
+	}
+
+	c.videos[f.ID] = NewVideo(&ytapi.Video{
+		Id:   f.ID,
+		Kind: "youtube#video",
+		Snippet: &ytapi.VideoSnippet{
+			Title:        f.Title,
+			Description:  f.Description,
+			ChannelId:    f.ChannelID,
+			ChannelTitle: f.ChannelTitle,
+			PublishedAt:  f.PublishedAt,
+			Thumbnails: &ytapi.ThumbnailDetails{
+				Default: &ytapi.Thumbnail{Url: f.ThumbnailURL},
+			},
+		},
+		ContentDetails: &ytapi.VideoContentDetails{Duration: f.Duration},
+		Statistics:     &ytapi.VideoStatistics{ViewCount: f.ViewCount, LikeCount: f.LikeCount},
+	})
+	for _, cf := range f.Comments {
+		c.comments[f.ID] = append(c.comments[f.ID], NewComment(cf.ID, f.ID, cf.AuthorChannelID, cf.Text))
+	}
+	c.titles = append(c.titles, f.ID)
+	return nil
+}
+
+// spend spends the quota cost of calls Data API calls of the given type, or returns
+// ErrQuotaBudgetExceeded once the budget is spent.
+func (c *fixtureClient) spend(call string, cost int64, calls int) error {
+	for i := 0; i < calls; i++ {
+		err := c.quota.Spend(call, cost)
+		if err != nil {
+			return fmt.Errorf("unable to do call: %w", err)
+		}
+	}
+	return nil
+}
+
+// pages returns the number of pages of perPage results needed to list n results. Listing no
+// results still takes a call.
+func pages(n, perPage int) int {
+	if n <= 0 {
+		return 1
+	}
+	return (n + perPage - 1) / perPage
+}
+
+// GetVideoByTitle returns the first fixture whose title contains title, ignoring case.
+func (c *fixtureClient) GetVideoByTitle(title string) (Video, error) {
+	err := c.spend(CallSearchList, CostSearchList, 1)
+	if err != nil {
+		return &video{}, err
+	}
+	for _, id := range c.titles {
+		vid := c.videos[id]
+		if strings.Contains(strings.ToLower(vid.GetTitle()), strings.ToLower(title)) {
+			return c.GetVideoByID(id)
+		}
+	}
+	return &video{}, fmt.Errorf("%w: no fixtures with title %s", ErrVideoNotFound, title)
+}
+
+func (c *fixtureClient) GetVideoByURL(rawURL string) (Video, error) {
+	url, err := NewURL(rawURL)
+	if err != nil {
+		return &video{}, fmt.Errorf("unable to create new URL from raw url %s: %w", rawURL, err)
+	}
+	return c.GetVideoByID(url.GetID())
+}
+
+func (c *fixtureClient) GetVideoByID(id string) (Video, error) {
+	err := c.spend(CallVideosList, CostVideosList, 1)
+	if err != nil {
+		return &video{}, err
+	}
+	return c.video(id)
+}
+
+// video returns the fixture for id, or the error it is configured to fail with.
+func (c *fixtureClient) video(id string) (Video, error) {
+	if err, ok := c.errors[id]; ok {
+		return &video{}, err
+	}
+	if vid, ok := c.videos[id]; ok {
+		return vid, nil
+	}
+	return &video{}, fmt.Errorf("%w: no fixture with id %s", ErrVideoNotFound, id)
+}
+
+// GetVideosByIDs returns the fixtures for ids, the same way the Data API client does: the
+// unique ids are looked up MaxIDsPerRequest at a time, videos that can't be watched are
+// reported in a *MissingVideosError, and any other error configured for one of the ids fails
+// the whole call.
+func (c *fixtureClient) GetVideosByIDs(ids []string) (map[string]Video, error) {
+	unique := map[string]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+	err := c.spend(CallVideosList, CostVideosList, pages(len(unique), MaxIDsPerRequest))
+	if err != nil {
+		return map[string]Video{}, err
+	}
+
+	videos := map[string]Video{}
+	missing := &MissingVideosError{Errors: map[string]error{}}
+	for _, id := range ids {
+		if _, ok := videos[id]; ok {
+			continue
+		}
+		if _, ok := missing.Errors[id]; ok {
+			continue
+		}
+		vid, err := c.video(id)
+		switch {
+		case err == nil:
+			videos[id] = vid
+		case IsRetryable(err), IsQuotaError(err):
+			return map[string]Video{}, err
+		default:
+			missing.IDs = append(missing.IDs, id)
+			missing.Errors[id] = err
+		}
+	}
+	if len(missing.IDs) > 0 {
+		return videos, missing
+	}
+	return videos, nil
+}
+
+// GetPlaylistByID returns the fixture for the playlist with the given id, with at most
+// maxItems videos, where 0 is unlimited, spending a call for each page of items the Data API
+// client would have listed.
+func (c *fixtureClient) GetPlaylistByID(id string, maxItems int) (Playlist, error) {
+	err := c.spend(CallPlaylistsList, CostPlaylistsList, 1)
+	if err != nil {
+		return &playlist{}, err
+	}
+	if err, ok := c.errors[id]; ok {
+		return &playlist{}, err
+	}
+	pl, ok := c.playlists[id]
+	if !ok {
+		return &playlist{}, fmt.Errorf("%w: no fixture with id %s", ErrPlaylistNotFound, id)
+	}
+
+	pl = firstItems(pl, maxItems)
+	err = c.spend(CallPlaylistItemsList, CostPlaylistItemsList, pages(len(pl.GetVideoIDs()), MaxIDsPerRequest))
+	if err != nil {
+		return &playlist{}, err
+	}
+	return pl, nil
+}
+
+func (c *fixtureClient) GetChannel(ref ChannelRef) (Channel, error) {
+	err := c.spend(CallChannelsList, CostChannelsList, 1)
+	if err != nil {
+		return &channel{}, err
+	}
+	id := ref.ID
+	if id == "" {
+		id = c.handles[strings.ToLower(ref.Handle)]
+	}
+	if err, ok := c.errors[id]; ok {
+		return &channel{}, err
+	}
+	if ch, ok := c.channels[id]; ok {
+		return ch, nil
+	}
+	return &channel{}, fmt.Errorf("%w: no fixture for %s", ErrChannelNotFound, ref)
+}
+
+// GetUploads returns the fixtures of the videos in ch that pass filter, the most recent first,
+// spending a call for each page of uploads the Data API client would have listed.
+func (c *fixtureClient) GetUploads(ch Channel, filter UploadsFilter) ([]string, error) {
+	uploads := []Video{}
+	for _, id := range c.titles {
+		vid := c.videos[id]
+		if vid.GetChannelID() == ch.GetID() {
+			uploads = append(uploads, vid)
+		}
+	}
+	sort.SliceStable(uploads, func(i, j int) bool { return uploads[i].GetPublishedAt() > uploads[j].GetPublishedAt() })
+
+	ids := []string{}
+	listed := 0
+	for _, vid := range uploads {
+		if filter.full(len(ids)) {
+			break
+		}
+		listed++
+		publishedAt, err := time.Parse(time.RFC3339, vid.GetPublishedAt())
+		if err != nil || !filter.includes(publishedAt) {
+			continue
+		}
+		ids = append(ids, vid.GetID())
+	}
+	err := c.spend(CallPlaylistItemsList, CostPlaylistItemsList, pages(listed, MaxIDsPerRequest))
+	if err != nil {
+		return []string{}, err
+	}
+	return ids, nil
+}
+
+// GetComments returns the comment fixtures on vid that pass filter, paging through them the
+// same way the Data API client does, MaxCommentsPerPage at a time.
+func (c *fixtureClient) GetComments(vid Video, filter CommentFilter) ([]Comment, error) {
+	all := c.comments[vid.GetID()]
+	if filter.MaxPages > 0 && len(all) > filter.MaxPages*MaxCommentsPerPage {
+		all = all[:filter.MaxPages*MaxCommentsPerPage]
+	}
+	err := c.spend(CallCommentThreadsList, CostCommentThreadsList, pages(len(all), MaxCommentsPerPage))
+	if err != nil {
+		return []Comment{}, err
+	}
+
+	comments := []Comment{}
+	for _, cm := range all {
+		if filter.includes(vid, cm.GetAuthorChannelID()) {
+			comments = append(comments, cm)
+		}
+	}
+	return comments, nil
+}
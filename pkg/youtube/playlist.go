@@ -0,0 +1,144 @@
+package youtube
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// playlistIDRegex matches a bare playlist ID, such as PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur
+	playlistIDRegex = `^(?:PL|UU|LL|FL|OL|RD)[\w-]{10,}$`
+
+	playlistURLFormat = "https://www.youtube.com/playlist?list=%s"
+)
+
+var (
+	playlistIDRe = regexp.MustCompile(playlistIDRegex)
+)
+
+// Playlist is an ordered list of videos.
+type Playlist interface {
+	GetID() string
+	GetTitle() string
+	GetChannelID() string
+	GetChannelTitle() string
+	// GetVideoIDs returns the IDs of the videos in the playlist, in playlist order, up to the
+	// maximum the playlist was looked up with
+	GetVideoIDs() []string
+	// GetItemCount returns the number of videos in the playlist, including any left out of GetVideoIDs
+	GetItemCount() int
+	GetURL() string
+}
+
+// PlaylistSource is implemented by any Source that is also able to look up playlists.
+type PlaylistSource interface {
+	// GetPlaylistByID returns the playlist with the given id, holding the IDs of its first
+	// maxItems videos, where 0 is unlimited
+	GetPlaylistByID(id string, maxItems int) (Playlist, error)
+}
+
+// GetPlaylist looks up the playlist with the given id, along with its first maxItems videos,
+// using src, returning ErrUnsupported if src is unable to look up playlists.
+func GetPlaylist(src Source, id string, maxItems int) (Playlist, error) {
+	ps, ok := src.(PlaylistSource)
+	if !ok {
+		return &playlist{}, fmt.Errorf("%w: unable to get playlist %s", ErrUnsupported, id)
+	}
+	return ps.GetPlaylistByID(id, maxItems)
+}
+
+type playlist struct {
+	id           string
+	title        string
+	channelID    string
+	channelTitle string
+	videoIDs     []string
+	itemCount    int
+}
+
+// NewPlaylist creates the Playlist with the given id, containing the videos with the given IDs.
+func NewPlaylist(id, title, channelID, channelTitle string, videoIDs []string) Playlist {
+	return &playlist{
+		id:           strings.TrimSpace(id),
+		title:        strings.TrimSpace(title),
+		channelID:    channelID,
+		channelTitle: channelTitle,
+		videoIDs:     videoIDs,
+		itemCount:    len(videoIDs),
+	}
+}
+
+// firstItems returns pl holding only its first maxItems videos, where 0 is unlimited.
+func firstItems(pl Playlist, maxItems int) Playlist {
+	ids := pl.GetVideoIDs()
+	if maxItems <= 0 || len(ids) <= maxItems {
+		return pl
+	}
+	return &playlist{
+		id:           pl.GetID(),
+		title:        pl.GetTitle(),
+		channelID:    pl.GetChannelID(),
+		channelTitle: pl.GetChannelTitle(),
+		videoIDs:     ids[:maxItems],
+		itemCount:    pl.GetItemCount(),
+	}
+}
+
+func (p *playlist) GetID() string {
+	return p.id
+}
+
+func (p *playlist) GetTitle() string {
+	return p.title
+}
+
+func (p *playlist) GetChannelID() string {
+	return p.channelID
+}
+
+func (p *playlist) GetChannelTitle() string {
+	return p.channelTitle
+}
+
+func (p *playlist) GetVideoIDs() []string {
+	return p.videoIDs
+}
+
+func (p *playlist) GetItemCount() int {
+	return p.itemCount
+}
+
+// GetURL returns the canonical URL of the playlist's page.
+func (p *playlist) GetURL() string {
+	return fmt.Sprintf(playlistURLFormat, p.id)
+}
+
+// NewPlaylistURL parses the playlist ID out of a link to a playlist, such as
+// https://www.youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur, a link to a video played
+// from a playlist, or a bare playlist ID.
+func NewPlaylistURL(rawURL string) (Url, error) {
+	ref, err := ParseReference(rawURL)
+	if err != nil {
+		return &url{}, err
+	}
+	switch {
+	case ref.Kind == KindPlaylist:
+		return &url{origin: rawURL, ytID: ref.ID}, nil
+	case ref.Kind == KindVideo && ref.PlaylistID != "":
+		return &url{origin: rawURL, ytID: ref.PlaylistID}, nil
+	}
+	return &url{}, fmt.Errorf("%w: Unable to parse playlist ID out of URL %s", ErrInvalidURL, rawURL)
+}
+
+// GetPlaylistUrlsFromText returns the unique links to playlists in text, in the order they appear.
+// Links to a video played from a playlist are links to the video, not the playlist.
+func GetPlaylistUrlsFromText(text string) []string {
+	urls := []string{}
+	for _, match := range findLinks(text) {
+		if match.err == nil && match.ref.Kind == KindPlaylist && !contains(urls, match.url) {
+			urls = append(urls, match.url)
+		}
+	}
+	return urls
+}
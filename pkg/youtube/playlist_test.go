@@ -0,0 +1,94 @@
+package youtube
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlaylistURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"},
+		{"youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"},
+		{"https://m.youtube.com/playlist?feature=share&list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"},
+		{"https://www.youtube.com/embed/videoseries?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"},
+		{"PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			url, err := NewPlaylistURL(tt.input)
+			require.NoError(t, err, "NewPlaylistURL produced an unexpected error")
+			require.Equal(t, tt.expected, url.GetID())
+		})
+	}
+
+	_, err := NewPlaylistURL("https://www.youtube.com/watch?v=iDIcydiQOhc")
+	require.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestGetPlaylistUrlsFromText(t *testing.T) {
+	text := "Full series playlist: youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur\n" +
+		"Watch the first episode: https://www.youtube.com/watch?v=iDIcydiQOhc&list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur\n" +
+		"Again: youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"
+	require.Equal(t, []string{"youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"}, GetPlaylistUrlsFromText(text))
+}
+
+func TestClient_GetPlaylistByIDPagesItems(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	pages := map[string]string{
+		"":      `{"nextPageToken":"page2","items":[{"contentDetails":{"videoId":"aaaaaaaaaaa"}},{"contentDetails":{"videoId":"bbbbbbbbbbb"}}]}`,
+		"page2": `{"items":[{"contentDetails":{"videoId":"ccccccccccc"}}]}`,
+	}
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"items":[{"id":"PLtest","snippet":{"title":"Test Playlist","channelId":"UCtest","channelTitle":"Test Channel"},"contentDetails":{"itemCount":3}}]}`
+		if strings.HasSuffix(req.URL.Path, "/playlistItems") {
+			var ok bool
+			body, ok = pages[req.URL.Query().Get("pageToken")]
+			require.True(t, ok, "unexpected page token")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+
+	tests := []struct {
+		name     string
+		maxItems int
+		expected []string
+		pages    int64
+	}{
+		{name: "unlimited", maxItems: 0, expected: []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}, pages: 2},
+		{name: "limit within the first page", maxItems: 2, expected: []string{"aaaaaaaaaaa", "bbbbbbbbbbb"}, pages: 1},
+		{name: "limit on the last page", maxItems: 3, expected: []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}, pages: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quota := NewQuotaTracker(0)
+			c, err := NewClient("key", log, WithTransport(api), WithQuotaTracker(quota))
+			require.NoError(t, err, "NewClient produced an unexpected error")
+
+			pl, err := GetPlaylist(c, "PLtest", tt.maxItems)
+			require.NoError(t, err, "GetPlaylist produced an unexpected error")
+			require.Equal(t, "Test Playlist", pl.GetTitle())
+			require.Equal(t, tt.expected, pl.GetVideoIDs())
+			require.Equal(t, 3, pl.GetItemCount(), "expected the item count to include the videos left out")
+			require.Equal(t, CostPlaylistsList+tt.pages*CostPlaylistItemsList, quota.Summary().Units, fmt.Sprintf("expected one %s call and %d %s calls", CallPlaylistsList, tt.pages, CallPlaylistItemsList))
+		})
+	}
+
+	_, err := GetPlaylist(newMapSource(ErrVideoNotFound), "PLtest", 0)
+	require.ErrorIs(t, err, ErrUnsupported)
+}
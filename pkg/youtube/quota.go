@@ -0,0 +1,99 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Quota costs of each Data API call made by the client.
+// https://developers.google.com/youtube/v3/determine_quota_cost
+const (
+	CallSearchList         = "search.list"
+	CallVideosList         = "videos.list"
+	CallPlaylistsList      = "playlists.list"
+	CallPlaylistItemsList  = "playlistItems.list"
+	CallChannelsList       = "channels.list"
+	CallCommentThreadsList = "commentThreads.list"
+
+	CostSearchList         int64 = 100
+	CostVideosList         int64 = 1
+	CostPlaylistsList      int64 = 1
+	CostPlaylistItemsList  int64 = 1
+	CostChannelsList       int64 = 1
+	CostCommentThreadsList int64 = 1
+)
+
+// ErrQuotaBudgetExceeded is returned instead of making a call that would exceed the quota budget.
+var ErrQuotaBudgetExceeded = errors.New("quota budget exceeded")
+
+// QuotaTracker counts the quota units spent by a client, per call type, and enforces an
+// optional budget. It is safe to use from multiple goroutines.
+type QuotaTracker struct {
+	mu     sync.Mutex
+	budget int64
+	spent  int64
+	usage  map[string]*CallUsage
+}
+
+// CallUsage is the number of calls made, and quota units spent, for a single call type.
+type CallUsage struct {
+	Call  string `json:"call"`
+	Calls int64  `json:"calls"`
+	Units int64  `json:"units"`
+}
+
+// QuotaSummary describes the quota units spent by a client.
+type QuotaSummary struct {
+	Budget int64       `json:"budget,omitempty"`
+	Units  int64       `json:"units"`
+	Calls  []CallUsage `json:"calls"`
+}
+
+// NewQuotaTracker creates a QuotaTracker that refuses to spend more than budget units.
+// A budget of 0 means the spending is tracked but never limited.
+func NewQuotaTracker(budget int64) *QuotaTracker {
+	return &QuotaTracker{
+		budget: budget,
+		usage:  map[string]*CallUsage{},
+	}
+}
+
+// Spend records a call costing the given units, or returns ErrQuotaBudgetExceeded without
+// recording anything if doing so would exceed the budget.
+func (q *QuotaTracker) Spend(call string, units int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.budget > 0 && q.spent+units > q.budget {
+		return fmt.Errorf("%w: %s costs %d units, %d of %d units already spent", ErrQuotaBudgetExceeded, call, units, q.spent, q.budget)
+	}
+
+	usage, ok := q.usage[call]
+	if !ok {
+		usage = &CallUsage{Call: call}
+		q.usage[call] = usage
+	}
+	usage.Calls++
+	usage.Units += units
+	q.spent += units
+	return nil
+}
+
+// Summary returns the units spent so far, with the usage of each call type sorted by name.
+func (q *QuotaTracker) Summary() QuotaSummary {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	summary := QuotaSummary{
+		Budget: q.budget,
+		Units:  q.spent,
+		Calls:  []CallUsage{},
+	}
+	for _, usage := range q.usage {
+		summary.Calls = append(summary.Calls, *usage)
+	}
+	sort.Slice(summary.Calls, func(i, j int) bool { return summary.Calls[i].Call < summary.Calls[j].Call })
+	return summary
+}
@@ -0,0 +1,31 @@
+package youtube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaTracker_Spend(t *testing.T) {
+	q := NewQuotaTracker(102)
+
+	require.NoError(t, q.Spend(CallSearchList, CostSearchList), "Spend produced an unexpected error")
+	require.NoError(t, q.Spend(CallVideosList, CostVideosList), "Spend produced an unexpected error")
+	require.NoError(t, q.Spend(CallVideosList, CostVideosList), "Spend produced an unexpected error")
+	require.ErrorIs(t, q.Spend(CallVideosList, CostVideosList), ErrQuotaBudgetExceeded)
+
+	summary := q.Summary()
+	require.Equal(t, int64(102), summary.Units)
+	require.Equal(t, []CallUsage{
+		{Call: CallSearchList, Calls: 1, Units: 100},
+		{Call: CallVideosList, Calls: 2, Units: 2},
+	}, summary.Calls)
+}
+
+func TestQuotaTracker_Unlimited(t *testing.T) {
+	q := NewQuotaTracker(0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, q.Spend(CallSearchList, CostSearchList), "Spend produced an unexpected error")
+	}
+	require.Equal(t, int64(10000), q.Summary().Units)
+}
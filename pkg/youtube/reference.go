@@ -0,0 +1,278 @@
+package youtube
+
+import (
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReferenceKind is the kind of object a link refers to.
+type ReferenceKind string
+
+// Kinds of object a link can refer to.
+const (
+	KindVideo    ReferenceKind = "video"
+	KindPlaylist ReferenceKind = "playlist"
+	KindChannel  ReferenceKind = "channel"
+	KindHandle   ReferenceKind = "handle"
+	KindClip     ReferenceKind = "clip"
+)
+
+const (
+	// videoIDRegex matches a video ID, such as iDIcydiQOhc
+	videoIDRegex = `^[\w-]{11}$`
+	// handleIDRegex matches a channel handle, such as @pbsspacetime
+	handleIDRegex = `^@[\w.-]{3,30}$`
+	// clipIDRegex matches a clip ID, such as UgkxT4s2IfrdK8Yk8mTCUvB1ENp7Vc4jQPgh
+	clipIDRegex = `^Ugk[\w-]{20,}$`
+	// linkCandidateRegex matches anything in a text that looks like a link to a YouTube host,
+	// stopping at whitespace and brackets, for ParseReference to make sense of
+	linkCandidateRegex = `(?i)(?:https?:\/\/)?(?:[\w-]+\.)*(?:youtube(?:-nocookie)?\.com|youtu\.be)\/[^\s<>"'()\[\]{}]*`
+	// externalLinkRegex matches any link with a scheme, stopping at whitespace and brackets
+	externalLinkRegex = `(?i)\bhttps?:\/\/[^\s<>"'()\[\]{}]+`
+	// paperIDRegex matches a DOI or arXiv ID written without a link, such as doi:10.1088/1367-2630/ab5c7d or arXiv:2103.12345
+	paperIDRegex = `(?i)\b(?:doi:\s?10\.\d{4,9}\/[^\s<>"'()\[\]{}]+|arxiv:\s?(?:\d{4}\.\d{4,5}|[a-z-]+(?:\.[a-z]{2})?\/\d{7})(?:v\d+)?)`
+	// timestampRegex matches a timestamp as either a number of seconds or a duration such as 1h2m3s
+	timestampRegex = `^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`
+
+	handleURLFormat = "https://www.youtube.com/%s"
+	clipURLFormat   = "https://www.youtube.com/clip/%s"
+)
+
+var (
+	videoIDRe       = regexp.MustCompile(videoIDRegex)
+	handleIDRe      = regexp.MustCompile(handleIDRegex)
+	clipIDRe        = regexp.MustCompile(clipIDRegex)
+	linkCandidateRe = regexp.MustCompile(linkCandidateRegex)
+	externalLinkRe  = regexp.MustCompile(externalLinkRegex)
+	paperIDRe       = regexp.MustCompile(paperIDRegex)
+	timestampRe     = regexp.MustCompile(timestampRegex)
+)
+
+// hosts contains every host serving YouTube pages, after any www. prefix is removed.
+var hosts = map[string]bool{
+	"youtube.com":          true,
+	"m.youtube.com":        true,
+	"music.youtube.com":    true,
+	"gaming.youtube.com":   true,
+	"youtube-nocookie.com": true,
+	"youtu.be":             true,
+}
+
+// videoPaths contains the first segment of every path that is followed by a video ID, such as /shorts/iDIcydiQOhc.
+var videoPaths = map[string]bool{
+	"shorts": true,
+	"live":   true,
+	"embed":  true,
+	"v":      true,
+	"e":      true,
+}
+
+// Reference is the object a link, or a bare ID, refers to.
+type Reference struct {
+	Kind ReferenceKind
+	// ID is the canonical ID of the object, which is the @handle of a channel referenced by handle
+	ID string
+	// Timestamp is the time into the video a link starts playing at, or 0 if it starts at the beginning
+	Timestamp time.Duration
+	// PlaylistID is the ID of the playlist a video is played from, if any
+	PlaylistID string
+}
+
+// URL returns the canonical link to the referenced object.
+func (r Reference) URL() string {
+	switch r.Kind {
+	case KindVideo:
+		return WatchURL(r.ID)
+	case KindPlaylist:
+		return fmt.Sprintf(playlistURLFormat, r.ID)
+	case KindChannel:
+		return fmt.Sprintf(channelURLFormat, r.ID)
+	case KindHandle:
+		return fmt.Sprintf(handleURLFormat, r.ID)
+	case KindClip:
+		return fmt.Sprintf(clipURLFormat, r.ID)
+	}
+	return ""
+}
+
+// ParseReference parses a link to a video, playlist, channel or clip on any YouTube host, such as
+// https://youtu.be/iDIcydiQOhc?t=90, https://m.youtube.com/shorts/iDIcydiQOhc or
+// https://www.youtube-nocookie.com/embed/iDIcydiQOhc, or a bare video, playlist or channel ID or
+// @handle. Links without a scheme are accepted. If the link is recognized but the ID in it is
+// invalid, the kind of the reference is returned along with an error wrapping ErrInvalidURL.
+func ParseReference(input string) (Reference, error) {
+	trimmed := strings.TrimSpace(input)
+	switch {
+	case videoIDRe.MatchString(trimmed):
+		return Reference{Kind: KindVideo, ID: trimmed}, nil
+	case playlistIDRe.MatchString(trimmed):
+		return Reference{Kind: KindPlaylist, ID: trimmed}, nil
+	case channelIDRe.MatchString(trimmed):
+		return Reference{Kind: KindChannel, ID: trimmed}, nil
+	case handleIDRe.MatchString(trimmed):
+		return Reference{Kind: KindHandle, ID: trimmed}, nil
+	}
+
+	if !strings.Contains(trimmed, "://") {
+		trimmed = "https://" + trimmed
+	}
+	u, err := neturl.Parse(trimmed)
+	if err != nil {
+		return Reference{}, fmt.Errorf("%w: Unable to parse URL %s: %s", ErrInvalidURL, input, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Reference{}, fmt.Errorf("%w: Unsupported scheme in URL %s", ErrInvalidURL, input)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if !isYoutubeHost(host) {
+		return Reference{}, fmt.Errorf("%w: %s is not a YouTube URL", ErrInvalidURL, input)
+	}
+
+	query := u.Query()
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case host == "youtu.be":
+		return videoReference(input, segments[0], query, u.Fragment)
+	case segments[0] == "watch":
+		return videoReference(input, query.Get("v"), query, u.Fragment)
+	case segments[0] == "embed" && len(segments) > 1 && segments[1] == "videoseries", segments[0] == "playlist":
+		return idReference(input, KindPlaylist, query.Get("list"), playlistIDRe)
+	case videoPaths[segments[0]] && len(segments) > 1:
+		return videoReference(input, segments[1], query, u.Fragment)
+	case segments[0] == "channel" && len(segments) > 1:
+		return idReference(input, KindChannel, segments[1], channelIDRe)
+	case segments[0] == "clip" && len(segments) > 1:
+		return idReference(input, KindClip, segments[1], clipIDRe)
+	case strings.HasPrefix(segments[0], "@"):
+		return idReference(input, KindHandle, segments[0], handleIDRe)
+	case segments[0] == "attribution_link" && strings.HasPrefix(query.Get("u"), "/"):
+		// Attribution links wrap the path of the link they attribute, such as /watch?v=iDIcydiQOhc
+		return ParseReference(host + query.Get("u"))
+	}
+	return Reference{}, fmt.Errorf("%w: Unable to parse a video, playlist or channel out of URL %s", ErrInvalidURL, input)
+}
+
+// isYoutubeHost returns true if host serves YouTube pages.
+func isYoutubeHost(host string) bool {
+	return hosts[strings.TrimPrefix(strings.ToLower(host), "www.")]
+}
+
+// videoReference returns the reference to the video with the given id, starting at the
+// timestamp given by the query or fragment of its link.
+func videoReference(input, id string, query neturl.Values, fragment string) (Reference, error) {
+	if !videoIDRe.MatchString(id) {
+		return Reference{Kind: KindVideo}, fmt.Errorf("%w: Unable to parse video ID out of URL %s", ErrInvalidURL, input)
+	}
+
+	// Timestamps are also given in the fragment, such as #t=90
+	if fragmentQuery, err := neturl.ParseQuery(fragment); err == nil {
+		for key, values := range fragmentQuery {
+			if query.Get(key) == "" {
+				query[key] = values
+			}
+		}
+	}
+	ts := parseTimestamp(query.Get("t"))
+	if ts == 0 {
+		ts = parseTimestamp(query.Get("start"))
+	}
+
+	playlistID := query.Get("list")
+	if !playlistIDRe.MatchString(playlistID) {
+		playlistID = ""
+	}
+	return Reference{Kind: KindVideo, ID: id, Timestamp: ts, PlaylistID: playlistID}, nil
+}
+
+// idReference returns the reference of the given kind to id, if it matches re.
+func idReference(input string, kind ReferenceKind, id string, re *regexp.Regexp) (Reference, error) {
+	if !re.MatchString(id) {
+		return Reference{Kind: kind}, fmt.Errorf("%w: Unable to parse %s ID out of URL %s", ErrInvalidURL, kind, input)
+	}
+	return Reference{Kind: kind, ID: id}, nil
+}
+
+// parseTimestamp returns the time given by a t or start parameter, such as 90, 90s or 1m30s,
+// or 0 if it isn't a timestamp.
+func parseTimestamp(value string) time.Duration {
+	parts := timestampRe.FindStringSubmatch(value)
+	if len(parts) != 4 {
+		return 0
+	}
+
+	var ts time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		if parts[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(parts[i+1])
+		if err != nil {
+			return 0
+		}
+		ts += time.Duration(n) * unit
+	}
+	return ts
+}
+
+// linkMatch is a single link to a YouTube host found in a text.
+type linkMatch struct {
+	url    string
+	offset int
+	ref    Reference
+	err    error
+}
+
+// findLinks returns every link to a YouTube host in text, in the order they appear, along with
+// what each of them refers to.
+func findLinks(text string) []linkMatch {
+	matches := []linkMatch{}
+	for _, loc := range linkCandidateRe.FindAllStringIndex(text, -1) {
+		// Skip hosts that merely end in a YouTube host, such as notyoutube.com
+		if loc[0] > 0 && (isWordByte(text[loc[0]-1]) || strings.IndexByte(".-/@", text[loc[0]-1]) >= 0) {
+			continue
+		}
+		// Punctuation ending a sentence isn't part of the link
+		raw := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?")
+		ref, err := ParseReference(raw)
+		matches = append(matches, linkMatch{url: raw, offset: loc[0], ref: ref, err: err})
+	}
+	return matches
+}
+
+// findExternalLinks returns every link in text that isn't to a YouTube host, such as a shortened
+// link, along with every DOI or arXiv ID written without a link, in the order they appear.
+func findExternalLinks(text string) []linkMatch {
+	matches := []linkMatch{}
+	links := externalLinkRe.FindAllStringIndex(text, -1)
+	for _, loc := range links {
+		raw := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?")
+		u, err := neturl.Parse(raw)
+		if err != nil || u.Hostname() == "" || isYoutubeHost(u.Hostname()) {
+			continue
+		}
+		matches = append(matches, linkMatch{url: raw, offset: loc[0]})
+	}
+
+	for _, loc := range paperIDRe.FindAllStringIndex(text, -1) {
+		// Skip the IDs that are part of a link, such as https://example.com/?id=doi:10.1000/1
+		inLink := false
+		for _, link := range links {
+			inLink = inLink || (link[0] <= loc[0] && loc[0] < link[1])
+		}
+		if !inLink {
+			matches = append(matches, linkMatch{url: strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?"), offset: loc[0]})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].offset < matches[j].offset })
+	return matches
+}
+
+// isWordByte returns true if b is an ASCII letter, digit or underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
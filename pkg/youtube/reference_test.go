@@ -0,0 +1,186 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	const (
+		videoID    = "iDIcydiQOhc"
+		playlistID = "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"
+		channelID  = "UC7_gcs09iThXybpVgjHZ_7g"
+		clipID     = "UgkxT4s2IfrdK8Yk8mTCUvB1ENp7Vc4jQPgh"
+	)
+	video := Reference{Kind: KindVideo, ID: videoID}
+	at := func(ts time.Duration) Reference { return Reference{Kind: KindVideo, ID: videoID, Timestamp: ts} }
+
+	tests := []struct {
+		input    string
+		expected Reference
+	}{
+		// Bare IDs
+		{videoID, video},
+		{"-IfmgyXs7z8", Reference{Kind: KindVideo, ID: "-IfmgyXs7z8"}},
+		{"  " + videoID + "\n", video},
+		{playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{channelID, Reference{Kind: KindChannel, ID: channelID}},
+		{"@pbsspacetime", Reference{Kind: KindHandle, ID: "@pbsspacetime"}},
+
+		// Watch pages
+		{"https://www.youtube.com/watch?v=" + videoID, video},
+		{"http://www.youtube.com/watch?v=" + videoID, video},
+		{"https://youtube.com/watch?v=" + videoID, video},
+		{"www.youtube.com/watch?v=" + videoID, video},
+		{"youtube.com/watch?v=" + videoID, video},
+		{"https://WWW.YouTube.com/watch?v=" + videoID, video},
+		{"https://www.youtube.com/watch/?v=" + videoID, video},
+		{"https://www.youtube.com/watch?feature=share&v=" + videoID, video},
+		{"https://www.youtube.com/watch?app=desktop&feature=youtu.be&v=" + videoID, video},
+		{"https://www.youtube.com/watch?v=" + videoID + "&feature=emb_title", video},
+		{"https://www.youtube.com/watch?v=" + videoID + "&ab_channel=PBSSpaceTime", video},
+
+		// Mobile, music and gaming hosts
+		{"https://m.youtube.com/watch?v=" + videoID, video},
+		{"m.youtube.com/watch?v=" + videoID + "&feature=share", video},
+		{"https://music.youtube.com/watch?v=" + videoID, video},
+		{"https://music.youtube.com/watch?v=" + videoID + "&list=" + playlistID, Reference{Kind: KindVideo, ID: videoID, PlaylistID: playlistID}},
+		{"https://gaming.youtube.com/watch?v=" + videoID, video},
+
+		// Short links
+		{"https://youtu.be/" + videoID, video},
+		{"youtu.be/" + videoID, video},
+		{"https://youtu.be/" + videoID + "/", video},
+		{"https://youtu.be/" + videoID + "?si=AbCdEfGhIjKlMnOp", video},
+		{"https://youtu.be/" + videoID + "?list=" + playlistID, Reference{Kind: KindVideo, ID: videoID, PlaylistID: playlistID}},
+
+		// Shorts, live streams and embeds
+		{"https://www.youtube.com/shorts/" + videoID, video},
+		{"https://youtube.com/shorts/" + videoID + "?feature=share", video},
+		{"https://m.youtube.com/shorts/" + videoID, video},
+		{"https://www.youtube.com/live/" + videoID, video},
+		{"https://www.youtube.com/live/" + videoID + "?si=AbCdEfGhIjKlMnOp&t=120", at(2 * time.Minute)},
+		{"https://www.youtube.com/embed/" + videoID, video},
+		{"https://www.youtube.com/embed/" + videoID + "?rel=0&autoplay=1", video},
+		{"https://www.youtube-nocookie.com/embed/" + videoID, video},
+		{"https://youtube-nocookie.com/embed/" + videoID + "?start=42", at(42 * time.Second)},
+		{"https://www.youtube.com/v/" + videoID, video},
+		{"https://www.youtube.com/e/" + videoID, video},
+		{"https://www.youtube.com/attribution_link?a=xyz&u=/watch%3Fv%3D" + videoID + "%26feature%3Dshare", video},
+
+		// Timestamps
+		{"https://youtu.be/" + videoID + "?t=90", at(90 * time.Second)},
+		{"https://youtu.be/" + videoID + "?t=90s", at(90 * time.Second)},
+		{"https://www.youtube.com/watch?v=" + videoID + "&t=1m30s", at(90 * time.Second)},
+		{"https://www.youtube.com/watch?v=" + videoID + "&t=1h2m3s", at(time.Hour + 2*time.Minute + 3*time.Second)},
+		{"https://www.youtube.com/watch?v=" + videoID + "&t=2h", at(2 * time.Hour)},
+		{"https://www.youtube.com/watch?v=" + videoID + "#t=15", at(15 * time.Second)},
+		{"https://www.youtube.com/watch?t=30&v=" + videoID, at(30 * time.Second)},
+		{"https://www.youtube.com/watch?v=" + videoID + "&t=soon", video},
+
+		// Playlists
+		{"https://www.youtube.com/playlist?list=" + playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{"youtube.com/playlist?list=" + playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{"https://m.youtube.com/playlist?feature=share&list=" + playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{"https://music.youtube.com/playlist?list=" + playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{"https://www.youtube.com/embed/videoseries?list=" + playlistID, Reference{Kind: KindPlaylist, ID: playlistID}},
+		{"https://www.youtube.com/watch?v=" + videoID + "&list=" + playlistID + "&index=2", Reference{Kind: KindVideo, ID: videoID, PlaylistID: playlistID}},
+
+		// Channels and handles
+		{"https://www.youtube.com/channel/" + channelID, Reference{Kind: KindChannel, ID: channelID}},
+		{"youtube.com/channel/" + channelID + "/videos", Reference{Kind: KindChannel, ID: channelID}},
+		{"https://www.youtube.com/@pbsspacetime", Reference{Kind: KindHandle, ID: "@pbsspacetime"}},
+		{"https://m.youtube.com/@pbsspacetime/featured", Reference{Kind: KindHandle, ID: "@pbsspacetime"}},
+		{"https://www.youtube.com/@pbs.space-time?sub_confirmation=1", Reference{Kind: KindHandle, ID: "@pbs.space-time"}},
+
+		// Clips
+		{"https://www.youtube.com/clip/" + clipID, Reference{Kind: KindClip, ID: clipID}},
+		{"https://youtube.com/clip/" + clipID + "?si=AbCdEfGhIjKlMnOp", Reference{Kind: KindClip, ID: clipID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := ParseReference(tt.input)
+			require.NoError(t, err, "ParseReference produced an unexpected error")
+			require.Equal(t, tt.expected, ref)
+		})
+	}
+}
+
+func TestParseReference_Invalid(t *testing.T) {
+	tests := []struct {
+		input string
+		// kind is the kind of a link that is recognized despite its invalid ID
+		kind ReferenceKind
+	}{
+		{input: ""},
+		{input: "not a link"},
+		{input: "iDIcydiQOh"},
+		{input: "https://example.com/watch?v=iDIcydiQOhc"},
+		{input: "https://notyoutube.com/watch?v=iDIcydiQOhc"},
+		{input: "https://youtube.com.evil.example/watch?v=iDIcydiQOhc"},
+		{input: "ftp://www.youtube.com/watch?v=iDIcydiQOhc"},
+		{input: "https://www.youtube.com/"},
+		{input: "https://www.youtube.com/feed/subscriptions"},
+		{input: "https://www.youtube.com/c/pbsspacetime"},
+		{input: "https://www.youtube.com/watch?v=short", kind: KindVideo},
+		{input: "https://www.youtube.com/watch?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", kind: KindVideo},
+		{input: "https://youtu.be/", kind: KindVideo},
+		{input: "https://youtu.be/iDIcydiQOhc123", kind: KindVideo},
+		{input: "https://www.youtube.com/shorts/<script>", kind: KindVideo},
+		{input: "https://www.youtube.com/playlist?list=", kind: KindPlaylist},
+		{input: "https://www.youtube.com/channel/UCtooshort", kind: KindChannel},
+		{input: "https://www.youtube.com/@a", kind: KindHandle},
+		{input: "https://www.youtube.com/clip/nope", kind: KindClip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := ParseReference(tt.input)
+			require.ErrorIs(t, err, ErrInvalidURL)
+			require.Equal(t, tt.kind, ref.Kind)
+		})
+	}
+}
+
+func TestReference_URL(t *testing.T) {
+	require.Equal(t, "https://www.youtube.com/watch?v=iDIcydiQOhc", Reference{Kind: KindVideo, ID: "iDIcydiQOhc"}.URL())
+	require.Equal(t, "https://www.youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur", Reference{Kind: KindPlaylist, ID: "PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur"}.URL())
+	require.Equal(t, "https://www.youtube.com/channel/UC7_gcs09iThXybpVgjHZ_7g", Reference{Kind: KindChannel, ID: "UC7_gcs09iThXybpVgjHZ_7g"}.URL())
+	require.Equal(t, "https://www.youtube.com/@pbsspacetime", Reference{Kind: KindHandle, ID: "@pbsspacetime"}.URL())
+}
+
+func TestGetUrlsFromText(t *testing.T) {
+	text := "Shorts: youtube.com/shorts/iDIcydiQOhc, live: https://www.youtube.com/live/-IfmgyXs7z8.\n" +
+		"Mobile (https://m.youtube.com/watch?feature=share&v=ztninkgZ0ws) and music https://music.youtube.com/watch?v=UwYSWAlAewc!\n" +
+		"Embedded: <iframe src=\"https://www.youtube-nocookie.com/embed/GHCc9b2phn0\"></iframe>\n" +
+		"Not videos: https://www.youtube.com/@pbsspacetime https://www.youtube.com/playlist?list=PLsPUh22kYmNAmjsHke4pd8S9z6m_hVRur notyoutube.com/watch?v=TbNymweHW4E\n" +
+		"Broken: https://youtu.be/short\n" +
+		"Again: youtube.com/shorts/iDIcydiQOhc"
+	require.Equal(t, []string{
+		"youtube.com/shorts/iDIcydiQOhc",
+		"https://www.youtube.com/live/-IfmgyXs7z8",
+		"https://m.youtube.com/watch?feature=share&v=ztninkgZ0ws",
+		"https://music.youtube.com/watch?v=UwYSWAlAewc",
+		"https://www.youtube-nocookie.com/embed/GHCc9b2phn0",
+		"https://youtu.be/short",
+	}, GetUrlsFromText(text))
+}
+
+func TestGetExternalLinksFromText(t *testing.T) {
+	text := "Previous episode: https://bit.ly/3series1.\n" +
+		"Also https://youtu.be/iDIcydiQOhc and www.example.com, but not notes.txt\n" +
+		"Shop (https://shop.example.com/merch?ref=yt) or https://bit.ly/3series1 again"
+	links := GetExternalLinksFromText(text)
+	require.Len(t, links, 3, "expected every occurrence of each link that isn't to YouTube, and only links with a scheme")
+	require.Equal(t, "https://bit.ly/3series1", links[0].URL, "expected the trailing punctuation to be left out")
+	require.Equal(t, "Previous episode", links[0].Context())
+	require.Equal(t, "https://shop.example.com/merch?ref=yt", links[1].URL)
+	require.Equal(t, "https://bit.ly/3series1", links[2].URL)
+
+	links = GetExternalLinksFromText("Based on arXiv:2103.12345v2 and doi:10.1088/1367-2630/ab5c7d.\nSee https://example.com/cite?id=doi:10.1000/182")
+	require.Len(t, links, 3, "expected DOIs and arXiv IDs, but not those within a link")
+	require.Equal(t, "arXiv:2103.12345v2", links[0].URL)
+	require.Equal(t, "doi:10.1088/1367-2630/ab5c7d", links[1].URL, "expected the trailing punctuation to be left out")
+	require.Equal(t, "https://example.com/cite?id=doi:10.1000/182", links[2].URL)
+}
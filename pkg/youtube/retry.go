@@ -0,0 +1,95 @@
+package youtube
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy describes how calls failing with a retryable error are retried. The delay
+// before each retry is chosen at random between 0 and BaseDelay*2^attempt, capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by clients created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// WithRetryPolicy retries calls that fail with a retryable error according to p.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *ytClient) {
+		c.retry = p
+	}
+}
+
+// lockedSource is a rand.Source that is safe to use from multiple goroutines, as a client is.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newRand returns a source of random delays seeded with seed, which is safe to use from
+// multiple goroutines.
+func newRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed)})
+}
+
+// delay returns the randomized delay before retrying the given attempt, which starts at 1,
+// drawn from rnd.
+func (p RetryPolicy) delay(attempt int, rnd *rand.Rand) time.Duration {
+	ceiling := p.BaseDelay << uint(attempt-1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rnd.Int63n(int64(ceiling) + 1))
+}
+
+// do calls fn, spending the quota cost of the call before each attempt and retrying
+// fn while it fails with a retryable error.
+func (c *ytClient) do(call string, cost int64, fn func() error) error {
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = c.quota.Spend(call, cost)
+		if err != nil {
+			return err
+		}
+
+		err = classifyError(call, fn())
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+
+		if attempt < attempts {
+			d := c.retry.delay(attempt, c.rand)
+			c.log.Warn("Retrying failed call", "call", call, "attempt", attempt, "delay", d, "error", err)
+			c.sleep(d)
+		}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package youtube
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func newTestClient(policy RetryPolicy) (*ytClient, *[]time.Duration) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+	delays := []time.Duration{}
+	return &ytClient{
+		log:   log,
+		quota: NewQuotaTracker(0),
+		retry: policy,
+		rand:  newRand(1),
+		sleep: func(d time.Duration) { delays = append(delays, d) },
+	}, &delays
+}
+
+func TestClassifyError(t *testing.T) {
+	forbidden := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}
+	tests := []struct {
+		name     string
+		call     string
+		err      error
+		expected error
+	}{
+		{"quota exceeded", CallVideosList, &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, ErrQuotaExceeded},
+		{"rate limited", CallVideosList, &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, ErrRateLimited},
+		{"too many requests", CallVideosList, &googleapi.Error{Code: http.StatusTooManyRequests}, ErrRateLimited},
+		{"forbidden video", CallVideosList, forbidden, ErrPrivateVideo},
+		{"forbidden comments", CallCommentThreadsList, forbidden, ErrForbidden},
+		{"forbidden channel", CallChannelsList, forbidden, ErrForbidden},
+		{"forbidden playlist items", CallPlaylistItemsList, forbidden, ErrForbidden},
+		{"forbidden without reason", CallVideosList, &googleapi.Error{Code: http.StatusForbidden}, ErrForbidden},
+		{"video not found", CallVideosList, &googleapi.Error{Code: http.StatusNotFound}, ErrVideoNotFound},
+		{"playlist not found", CallPlaylistsList, &googleapi.Error{Code: http.StatusNotFound}, ErrPlaylistNotFound},
+		{"playlist items not found", CallPlaylistItemsList, &googleapi.Error{Code: http.StatusNotFound}, ErrPlaylistNotFound},
+		{"channel not found", CallChannelsList, &googleapi.Error{Code: http.StatusNotFound}, ErrChannelNotFound},
+		{"server error", CallVideosList, &googleapi.Error{Code: http.StatusInternalServerError}, ErrUnavailable},
+		{"bad request", CallVideosList, &googleapi.Error{Code: http.StatusBadRequest}, ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.call, tt.err)
+			require.ErrorIs(t, err, tt.expected)
+
+			var apiErr *googleapi.Error
+			require.True(t, errors.As(err, &apiErr), "expected the *googleapi.Error to be kept in the chain")
+			require.Equal(t, tt.err, apiErr)
+		})
+	}
+	require.NotErrorIs(t, classifyError(CallCommentThreadsList, forbidden), ErrPrivateVideo, "expected only video lookups to be private")
+}
+
+func TestClient_DoRetriesRetryableErrors(t *testing.T) {
+	c, delays := newTestClient(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second})
+
+	calls := 0
+	err := c.do(CallVideosList, CostVideosList, func() error {
+		calls++
+		if calls < 3 {
+			return &googleapi.Error{Code: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	require.NoError(t, err, "do produced an unexpected error")
+	require.Equal(t, 3, calls)
+	require.Len(t, *delays, 2)
+	require.Equal(t, int64(3), c.quota.Summary().Units, "expected every attempt to spend quota")
+}
+
+func TestClient_DoStopsOnPermanentErrors(t *testing.T) {
+	c, delays := newTestClient(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second})
+
+	calls := 0
+	err := c.do(CallVideosList, CostVideosList, func() error {
+		calls++
+		return &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}
+	})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.False(t, errors.Is(err, ErrRateLimited))
+	require.Equal(t, 1, calls)
+	require.Empty(t, *delays)
+}
+
+func TestRetryPolicy_DelayIsCapped(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	rnd := newRand(1)
+	for attempt := 1; attempt < 10; attempt++ {
+		d := p.delay(attempt, rnd)
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		require.LessOrEqual(t, d, p.MaxDelay)
+	}
+}
+
+func TestRetryPolicy_DelayIsSeeded(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute}
+	a, b := newRand(42), newRand(42)
+	for attempt := 1; attempt < 10; attempt++ {
+		require.Equal(t, p.delay(attempt, a), p.delay(attempt, b), "expected the same seed to produce the same delays")
+	}
+}
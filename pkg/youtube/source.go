@@ -0,0 +1,176 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/inconshreveable/log15"
+)
+
+// ErrUnsupported is returned by a Source that is unable to perform the requested lookup.
+var ErrUnsupported = errors.New("operation not supported by source")
+
+// Source is anything able to look up videos, such as the Data API client or a scraper.
+type Source interface {
+	GetVideoByTitle(title string) (Video, error)
+	GetVideoByURL(rawURL string) (Video, error)
+	GetVideoByID(id string) (Video, error)
+	GetVideosByIDs(ids []string) (map[string]Video, error)
+}
+
+// NamedSource is a Source along with a name identifying it in logs.
+type NamedSource struct {
+	Name   string
+	Source Source
+}
+
+// fallbackSource tries each of its sources in order, until one of them succeeds.
+type fallbackSource struct {
+	sources []NamedSource
+	log     log15.Logger
+}
+
+// NewFallbackSource creates a Source that performs each lookup using the first of sources that
+// succeeds, such as trying a cache, then the Data API, then scraping.
+func NewFallbackSource(log log15.Logger, sources ...NamedSource) (Source, error) {
+	if len(sources) == 0 {
+		return &fallbackSource{}, fmt.Errorf("at least one source is required")
+	}
+	return &fallbackSource{
+		sources: sources,
+		log:     log,
+	}, nil
+}
+
+func (f *fallbackSource) GetVideoByTitle(title string) (Video, error) {
+	return f.first(title, func(src Source) (Video, error) { return src.GetVideoByTitle(title) })
+}
+
+func (f *fallbackSource) GetVideoByURL(rawURL string) (Video, error) {
+	return f.first(rawURL, func(src Source) (Video, error) { return src.GetVideoByURL(rawURL) })
+}
+
+func (f *fallbackSource) GetVideoByID(id string) (Video, error) {
+	return f.first(id, func(src Source) (Video, error) { return src.GetVideoByID(id) })
+}
+
+// GetVideosByIDs asks each source for the ids that the previous sources could not return.
+// If some ids can't be returned by any source, the error of the last source is returned.
+func (f *fallbackSource) GetVideosByIDs(ids []string) (map[string]Video, error) {
+	videos := map[string]Video{}
+	remaining := ids
+	var err error
+	for _, src := range f.sources {
+		var found map[string]Video
+		found, err = src.Source.GetVideosByIDs(remaining)
+		for id, vid := range found {
+			videos[id] = vid
+		}
+
+		next := []string{}
+		for _, id := range remaining {
+			if _, ok := videos[id]; !ok {
+				next = append(next, id)
+			}
+		}
+		remaining = next
+		if len(remaining) == 0 {
+			return videos, nil
+		}
+		f.log.Debug("Source unable to return videos, falling back", "source", src.Name, "ids", remaining, "error", err)
+	}
+
+	var missingErr *MissingVideosError
+	if errors.As(err, &missingErr) {
+		return videos, &MissingVideosError{IDs: remaining, Errors: missingErr.Errors}
+	} else if err == nil {
+		return videos, &MissingVideosError{IDs: remaining}
+	}
+	return videos, err
+}
+
+// GetPlaylistByID returns the playlist from the first source able to look up playlists that succeeds.
+func (f *fallbackSource) GetPlaylistByID(id string, maxItems int) (Playlist, error) {
+	var pl Playlist = &playlist{}
+	err := fmt.Errorf("%w: unable to get playlist %s", ErrUnsupported, id)
+	for _, src := range f.sources {
+		ps, ok := src.Source.(PlaylistSource)
+		if !ok {
+			continue
+		}
+		pl, err = ps.GetPlaylistByID(id, maxItems)
+		if err == nil {
+			return pl, nil
+		}
+		f.log.Debug("Source unable to return playlist, falling back", "source", src.Name, "id", id, "error", err)
+	}
+	return pl, err
+}
+
+// GetChannel returns the channel from the first source able to look up channels that succeeds.
+func (f *fallbackSource) GetChannel(ref ChannelRef) (Channel, error) {
+	var ch Channel = &channel{}
+	err := fmt.Errorf("%w: unable to get channel %s", ErrUnsupported, ref)
+	for _, src := range f.sources {
+		cs, ok := src.Source.(ChannelSource)
+		if !ok {
+			continue
+		}
+		ch, err = cs.GetChannel(ref)
+		if err == nil {
+			return ch, nil
+		}
+		f.log.Debug("Source unable to return channel, falling back", "source", src.Name, "channel", ref, "error", err)
+	}
+	return ch, err
+}
+
+// GetUploads returns the uploads from the first source able to look up channels that succeeds.
+func (f *fallbackSource) GetUploads(ch Channel, filter UploadsFilter) ([]string, error) {
+	ids := []string{}
+	err := fmt.Errorf("%w: unable to get uploads of channel %s", ErrUnsupported, ch.GetID())
+	for _, src := range f.sources {
+		cs, ok := src.Source.(ChannelSource)
+		if !ok {
+			continue
+		}
+		ids, err = cs.GetUploads(ch, filter)
+		if err == nil {
+			return ids, nil
+		}
+		f.log.Debug("Source unable to return uploads, falling back", "source", src.Name, "channel", ch.GetID(), "error", err)
+	}
+	return ids, err
+}
+
+// GetComments returns the comments from the first source able to look up comments that succeeds.
+func (f *fallbackSource) GetComments(vid Video, filter CommentFilter) ([]Comment, error) {
+	comments := []Comment{}
+	err := fmt.Errorf("%w: unable to get comments on video %s", ErrUnsupported, vid.GetID())
+	for _, src := range f.sources {
+		cs, ok := src.Source.(CommentSource)
+		if !ok {
+			continue
+		}
+		comments, err = cs.GetComments(vid, filter)
+		if err == nil {
+			return comments, nil
+		}
+		f.log.Debug("Source unable to return comments, falling back", "source", src.Name, "video", vid.GetID(), "error", err)
+	}
+	return comments, err
+}
+
+// first returns the result of the first source for which lookup succeeds, or the error of the last source.
+func (f *fallbackSource) first(input string, lookup func(src Source) (Video, error)) (Video, error) {
+	var vid Video
+	var err error
+	for _, src := range f.sources {
+		vid, err = lookup(src.Source)
+		if err == nil {
+			return vid, nil
+		}
+		f.log.Debug("Source unable to return video, falling back", "source", src.Name, "input", input, "error", err)
+	}
+	return vid, err
+}
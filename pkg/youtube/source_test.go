@@ -0,0 +1,85 @@
+package youtube
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inconshreveable/log15"
+	"github.com/stretchr/testify/require"
+	ytapi "google.golang.org/api/youtube/v3"
+)
+
+// mapSource is a Source serving the videos in its map, failing every other lookup with err.
+type mapSource struct {
+	videos map[string]Video
+	err    error
+}
+
+func newMapSource(err error, ids ...string) *mapSource {
+	src := &mapSource{videos: map[string]Video{}, err: err}
+	for _, id := range ids {
+		src.videos[id] = NewVideo(&ytapi.Video{Id: id, Snippet: &ytapi.VideoSnippet{Title: "Video " + id}})
+	}
+	return src
+}
+
+func (s *mapSource) GetVideoByTitle(title string) (Video, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *mapSource) GetVideoByURL(rawURL string) (Video, error) {
+	return s.GetVideoByID(rawURL)
+}
+
+func (s *mapSource) GetVideoByID(id string) (Video, error) {
+	if vid, ok := s.videos[id]; ok {
+		return vid, nil
+	}
+	return nil, fmt.Errorf("%s: %w", id, s.err)
+}
+
+func (s *mapSource) GetVideosByIDs(ids []string) (map[string]Video, error) {
+	videos := map[string]Video{}
+	missing := []string{}
+	for _, id := range ids {
+		if vid, ok := s.videos[id]; ok {
+			videos[id] = vid
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		if s.err == ErrVideoNotFound {
+			return videos, &MissingVideosError{IDs: missing}
+		}
+		return videos, s.err
+	}
+	return videos, nil
+}
+
+func TestFallbackSource(t *testing.T) {
+	log := log15.New()
+	log.SetHandler(log15.DiscardHandler())
+
+	cache := newMapSource(ErrVideoNotFound, "aaaaaaaaaaa")
+	api := newMapSource(ErrQuotaExceeded, "bbbbbbbbbbb")
+	scraper := newMapSource(ErrVideoNotFound, "bbbbbbbbbbb", "ccccccccccc")
+	src, err := NewFallbackSource(log, NamedSource{"cache", cache}, NamedSource{"api", api}, NamedSource{"scrape", scraper})
+	require.NoError(t, err, "NewFallbackSource produced an unexpected error")
+
+	vid, err := src.GetVideoByID("ccccccccccc")
+	require.NoError(t, err, "GetVideoByID produced an unexpected error")
+	require.Equal(t, "ccccccccccc", vid.GetID())
+
+	_, err = src.GetVideoByID("ddddddddddd")
+	require.ErrorIs(t, err, ErrVideoNotFound, "expected the error of the last source")
+
+	videos, err := src.GetVideosByIDs([]string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "ddddddddddd"})
+	require.Len(t, videos, 3)
+	var missingErr *MissingVideosError
+	require.ErrorAs(t, err, &missingErr)
+	require.Equal(t, []string{"ddddddddddd"}, missingErr.IDs)
+
+	_, err = NewFallbackSource(log)
+	require.Error(t, err, "expected at least one source to be required")
+}
@@ -2,40 +2,37 @@ package youtube
 
 import (
 	"fmt"
-	"regexp"
-)
-
-const (
-	// TODO: Help wanted: Improve this regex
-	vidIDregex = `((watch\?v=\S{11}){1})|(youtu\.be\/-?\w{10}\w?)|(^\w{11}$)`
+	"time"
 )
 
 type Url interface {
 	GetID() string
 	GetOrigin() string
+	// GetTimestamp returns the time into the video the link starts playing at, or 0 if it starts at the beginning
+	GetTimestamp() time.Duration
 }
 
 type url struct {
-	origin string
-	ytID   string
+	origin    string
+	ytID      string
+	timestamp time.Duration
 }
 
+// NewURL parses the video ID out of any link to a video accepted by ParseReference, such as
+// "https://www.youtube.com/watch?v=iDIcydiQOhc", or out of a bare video ID.
 func NewURL(ytURL string) (Url, error) {
-	// Given "https://www.youtube.com/watch?v=iDIcydiQOhc", extract iDIcydiQOhc
-	// and query yt API for that ID
-	re := regexp.MustCompile(vidIDregex)
-	res := re.FindAllStringSubmatch(ytURL, -1)
-	if len(res) != 1 {
-		return &url{}, fmt.Errorf("ERROR: invalid input string; Unable to parse video ID out of URL; Expected format %s, got result %v", vidIDregex, res)
+	ref, err := ParseReference(ytURL)
+	if err != nil {
+		return &url{}, err
+	}
+	if ref.Kind != KindVideo {
+		return &url{}, fmt.Errorf("%w: URL %s links to a %s, not a video", ErrInvalidURL, ytURL, ref.Kind)
 	}
-
-	// Pull the last 11 chars out of the match
-	id := res[0][0][len(res[0][0])-11:]
-	//fmt.Printf("Parsed id %s out of url %s\n", id, ytURL)
 
 	return &url{
-		origin: ytURL,
-		ytID:   id,
+		origin:    ytURL,
+		ytID:      ref.ID,
+		timestamp: ref.Timestamp,
 	}, nil
 }
 
@@ -46,3 +43,7 @@ func (u *url) GetID() string {
 func (u *url) GetOrigin() string {
 	return u.origin
 }
+
+func (u *url) GetTimestamp() time.Duration {
+	return u.timestamp
+}
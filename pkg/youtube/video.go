@@ -1,16 +1,15 @@
 package youtube
 
 import (
-	"regexp"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"google.golang.org/api/youtube/v3"
 )
 
 const (
-	//urlRegex = `(https?:\/\/(?:www\.|(^www))[a-zA-Z0-9][a-zA-Z0-9-]+[a-zA-Z0-9]\.[^\s]{2,}|www\.[a-zA-Z0-9][a-zA-Z0-9-]+[a-zA-Z0-9]\.[^\s]{2,}|https?:\/\/(?:www\.|(^www))[a-zA-Z0-9]+\.[^\s]{2,}|www\.[a-zA-Z0-9]+\.[^\s]{2,})`
-	//urlRegex = `(http(s)?:\/\/)?((w){3}.)?youtu(be|.be)?(\.com)?\/.+`
-	urlRegex = `(?:https?:\/\/)?(?:www\.)?(?:youtu\.be\/|youtube\.com\/(?:embed\/|v\/|watch\?v=|watch\?.+&v=))((\w|-){11})?`
+	watchURLFormat = "https://www.youtube.com/watch?v=%s"
 )
 
 type Video interface {
@@ -19,8 +18,17 @@ type Video interface {
 	GetDescription() string
 	GetThumbnailURL() string
 	GetUrlsFromDescription() []string
+	GetLinksFromDescription() []Link
+	GetChapters() []Chapter
+	GetPlaylistUrlsFromDescription() []string
 	GetChannelID() string
 	GetChannelTitle() string
+	GetPublishedAt() string
+	GetDuration() string
+	GetViewCount() uint64
+	GetLikeCount() uint64
+	GetEmbedHTML() string
+	GetWatchURL() string
 }
 
 type video struct {
@@ -28,6 +36,15 @@ type video struct {
 	youtube.Video
 }
 
+// NewVideo returns the Video described by vid, a youtube Data API video resource. It allows
+// sources other than the Data API, such as a scraper, to return the same Video.
+func NewVideo(vid *youtube.Video) Video {
+	if vid.Snippet == nil {
+		vid.Snippet = &youtube.VideoSnippet{}
+	}
+	return newVideo(vid)
+}
+
 func newVideo(vid *youtube.Video) Video {
 	v := &video{}
 
@@ -66,45 +83,186 @@ func (v *video) GetDescription() string {
 	return strings.TrimSpace(v.Snippet.Description)
 }
 
+// GetThumbnailURL returns the URL of the highest resolution thumbnail available for the video.
 func (v *video) GetThumbnailURL() string {
-	return strings.TrimSpace(v.Snippet.Thumbnails.Maxres.Url)
+	if v.Snippet == nil || v.Snippet.Thumbnails == nil {
+		return ""
+	}
+	t := v.Snippet.Thumbnails
+	for _, thumbnail := range []*youtube.Thumbnail{t.Maxres, t.Standard, t.High, t.Medium, t.Default} {
+		if thumbnail != nil && strings.TrimSpace(thumbnail.Url) != "" {
+			return strings.TrimSpace(thumbnail.Url)
+		}
+	}
+	return ""
 }
 
 func (v *video) GetUrlsFromDescription() []string {
-	re := regexp.MustCompile(urlRegex)
-	res := re.FindAllStringSubmatch(v.Snippet.Description, -1)
+	return GetUrlsFromText(v.Snippet.Description)
+}
+
+// GetUrlsFromText returns the unique links to videos in text, in the order they appear.
+func GetUrlsFromText(text string) []string {
 	urls := []string{}
-	for _, matchGroup := range res {
-		if len(matchGroup) == 0 {
-			continue
+	for _, link := range GetLinksFromText(text) {
+		if !contains(urls, link.URL) {
+			urls = append(urls, link.URL)
 		}
+	}
+	return urls
+}
 
-		// The first match in the regex matches the most subgroups
-		match := strings.TrimSpace(matchGroup[0])
+// Link is a single occurrence of a link in a text, such as a description, along with the text
+// surrounding it.
+type Link struct {
+	URL string
+	// Offset is the position of the link in the text, in bytes
+	Offset int
+	// Line is the whole line of the text containing the link
+	Line string
+	// PrecedingLine is the closest line before Line that isn't blank, if any
+	PrecedingLine string
+	// Chapter is the chapter of the text the link appears under, if the text lists chapters
+	Chapter *Chapter
+}
 
-		// Skip empty matches
-		if match == "" {
-			continue
+// Context returns the text describing the link, which is its line without any links, or the
+// preceding line if the link is on a line of its own, such as after "Watch the original episode here:".
+func (l Link) Context() string {
+	if context := contextText(l.Line); context != "" {
+		return context
+	}
+	return contextText(l.PrecedingLine)
+}
+
+// contextText returns line without any links, chapter timestamp or surrounding punctuation.
+func contextText(line string) string {
+	line = anyLinkRe.ReplaceAllString(line, "")
+	if res := chapterRe.FindStringSubmatch(line); len(res) == 5 {
+		line = res[4]
+	}
+	return strings.Trim(strings.Join(strings.Fields(line), " "), " -–—|:")
+}
+
+// GetLinksFromText returns every occurrence of a link to a video in text, in the order they
+// appear, along with the text surrounding it.
+func GetLinksFromText(text string) []Link {
+	matches := []linkMatch{}
+	for _, match := range findLinks(text) {
+		// Links recognized as links to a video but without a valid ID are kept, so that they
+		// can be reported as broken
+		if match.ref.Kind == KindVideo {
+			matches = append(matches, match)
 		}
+	}
+	return newLinks(text, matches)
+}
 
-		// Only track unique matches
-		if !contains(urls, match) {
-			//fmt.Printf("Found url: %s\n", match)
-			urls = append(urls, match)
+// GetExternalLinksFromText returns every occurrence of a link in text that isn't to a YouTube
+// host, such as shortened links that may redirect to a video or links to papers, in the order
+// they appear, along with the text surrounding it. DOIs and arXiv IDs written without a
+// link, such as doi:10.1088/1367-2630/ab5c7d, are included as they are written.
+func GetExternalLinksFromText(text string) []Link {
+	return newLinks(text, findExternalLinks(text))
+}
+
+// newLinks returns a link for each of matches, found in text, along with the text surrounding
+// it and the chapter it appears under. A link written more than once is returned once for each
+// occurrence, as each may appear under a different chapter.
+func newLinks(text string, matches []linkMatch) []Link {
+	chapters := ParseChapters(text)
+	links := []Link{}
+	for _, match := range matches {
+		line, preceding := surroundingLines(text, match.offset)
+		link := Link{URL: match.url, Offset: match.offset, Line: line, PrecedingLine: preceding}
+		if chapter, ok := chapterAt(chapters, match.offset); ok {
+			link.Chapter = &chapter
 		}
+		links = append(links, link)
 	}
-	return urls
+	return links
+}
+
+// surroundingLines returns the line of text containing offset, and the closest line before it
+// that isn't blank.
+func surroundingLines(text string, offset int) (string, string) {
+	start := strings.LastIndex(text[:offset], "\n") + 1
+	end := strings.Index(text[offset:], "\n")
+	if end < 0 {
+		end = len(text)
+	} else {
+		end += offset
+	}
+	line := strings.TrimSpace(text[start:end])
+
+	preceding := ""
+	before := strings.Split(text[:start], "\n")
+	for i := len(before) - 1; i >= 0 && preceding == ""; i-- {
+		preceding = strings.TrimSpace(before[i])
+	}
+	return line, preceding
+}
+
+// GetLinksFromDescription returns every occurrence of a link to a video in the description,
+// along with the chapter it appears under.
+func (v *video) GetLinksFromDescription() []Link {
+	return GetLinksFromText(v.Snippet.Description)
+}
+
+// GetChapters returns the chapters listed in the description.
+func (v *video) GetChapters() []Chapter {
+	return ParseChapters(v.Snippet.Description)
+}
+
+// GetPlaylistUrlsFromDescription returns the unique links to playlists in the description.
+func (v *video) GetPlaylistUrlsFromDescription() []string {
+	return GetPlaylistUrlsFromText(v.Snippet.Description)
 }
 
 func (v *video) GetEmbedHTML() string {
+	if v.Player == nil {
+		return ""
+	}
 	return v.Player.EmbedHtml
 }
 
 // https://en.wikipedia.org/wiki/ISO_8601#Durations
 func (v *video) GetDuration() string {
+	if v.ContentDetails == nil {
+		return ""
+	}
 	return v.ContentDetails.Duration
 }
 
+// GetPublishedAt returns the time the video was published, in RFC 3339 format.
+func (v *video) GetPublishedAt() string {
+	return v.Snippet.PublishedAt
+}
+
+func (v *video) GetViewCount() uint64 {
+	if v.Statistics == nil {
+		return 0
+	}
+	return v.Statistics.ViewCount
+}
+
+func (v *video) GetLikeCount() uint64 {
+	if v.Statistics == nil {
+		return 0
+	}
+	return v.Statistics.LikeCount
+}
+
+// GetWatchURL returns the canonical URL of the video's watch page.
+func (v *video) GetWatchURL() string {
+	return WatchURL(v.GetID())
+}
+
+// WatchURL returns the canonical URL of the watch page of the video with the given id.
+func WatchURL(id string) string {
+	return fmt.Sprintf(watchURLFormat, id)
+}
+
 func (v *video) GetChannelTitle() string {
 	return v.Snippet.ChannelTitle
 }
@@ -123,3 +281,22 @@ func contains(someList []string, someElement string) bool {
 	}
 	return contains
 }
+
+// MarshalVideo returns the JSON representation of v, following the youtube Data API's
+// video resource, allowing a video to be stored and later restored with UnmarshalVideo.
+func MarshalVideo(v Video) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// UnmarshalVideo returns the Video described by b, which must be a youtube Data API video resource.
+func UnmarshalVideo(b []byte) (Video, error) {
+	vid := &youtube.Video{}
+	err := json.Unmarshal(b, vid)
+	if err != nil {
+		return &video{}, fmt.Errorf("unable to unmarshal video: %s", err)
+	}
+	if strings.TrimSpace(vid.Id) == "" {
+		return &video{}, fmt.Errorf("unable to unmarshal video: missing id")
+	}
+	return NewVideo(vid), nil
+}